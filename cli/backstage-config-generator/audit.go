@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityCritical:
+		return "CRITICAL"
+	case SeverityWarning:
+		return "WARNING"
+	default:
+		return "INFO"
+	}
+}
+
+// Finding is a single issue reported by the security audit
+type Finding struct {
+	Severity Severity
+	Path     string
+	Message  string
+}
+
+// placeholderPattern matches values that are resolved by Backstage at runtime, like ${GITHUB_TOKEN}
+var placeholderPattern = regexp.MustCompile(`^\$\{[A-Za-z_][A-Za-z0-9_]*\}$`)
+
+func isPlaceholder(value string) bool {
+	return placeholderPattern.MatchString(value)
+}
+
+// auditConfig checks the final Config for insecure settings. The production flag
+// raises the severity of settings that are acceptable for local development only.
+func auditConfig(config *Config, production bool) []Finding {
+	var findings []Finding
+	add := func(severity Severity, path, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	devOnly := SeverityInfo
+	if production {
+		devOnly = SeverityCritical
+	}
+
+	for path, url := range map[string]string{"app.baseUrl": config.App.BaseUrl, "backend.baseUrl": config.Backend.BaseUrl} {
+		if strings.HasPrefix(url, "http://") {
+			add(devOnly, path, "base URL %s is served over plaintext HTTP", url)
+		}
+	}
+
+	if config.Backend.Database.Connection.Path == ":memory:" {
+		add(devOnly, "backend.database.connection", "in-memory database loses all catalog data on restart")
+	}
+
+	for _, src := range config.Backend.CSP.ConnectSrc {
+		switch src {
+		case "*":
+			add(SeverityCritical, "backend.csp.connect-src", "wildcard source '*' allows connections to any origin")
+		case "http:", "https:":
+			// Part of the Backstage defaults, so plugins can call the APIs they are configured with
+			severity := SeverityInfo
+			if production {
+				severity = SeverityWarning
+			}
+			add(severity, "backend.csp.connect-src", "scheme source '%s' allows connections to any host", src)
+		}
+	}
+
+	if config.Backend.CORS.Origin == "*" {
+		severity := SeverityWarning
+		if config.Backend.CORS.Credentials {
+			severity = SeverityCritical
+		}
+		add(severity, "backend.cors.origin", "CORS allows requests from any origin")
+	} else if strings.HasPrefix(config.Backend.CORS.Origin, "http://") {
+		add(devOnly, "backend.cors.origin", "CORS origin %s is served over plaintext HTTP", config.Backend.CORS.Origin)
+	}
+
+	for _, secret := range plaintextSecrets(secretFields(config)) {
+		add(SeverityCritical, secret.Path, "secret is stored in plaintext, use an environment variable placeholder like ${VAR} instead")
+	}
+
+	if config.Proxy != nil {
+		for path, endpoint := range config.Proxy.Endpoints {
+			if endpoint.Credentials == "dangerously-allow-unauthenticated" {
+				add(SeverityWarning, fmt.Sprintf("proxy.endpoints.%s.credentials", path), "endpoint can be called without a Backstage identity")
+			}
+			if endpoint.Secure != nil && !*endpoint.Secure {
+				add(SeverityWarning, fmt.Sprintf("proxy.endpoints.%s.secure", path), "TLS verification is disabled for target %s", endpoint.Target)
+			}
+		}
+	}
+
+	if config.Kubernetes != nil {
+		for i, locator := range config.Kubernetes.ClusterLocatorMethods {
+			if locator.SkipTLSVerify {
+				add(SeverityCritical, fmt.Sprintf("kubernetes.clusterLocatorMethods[%d].skipTLSVerify", i), "TLS verification is disabled for %s clusters", locator.Type)
+			}
+			for j, cluster := range locator.Clusters {
+				path := fmt.Sprintf("kubernetes.clusterLocatorMethods[%d].clusters[%d]", i, j)
+				if cluster.SkipTLSVerify {
+					if cluster.CAData == "" && cluster.CAFile == "" {
+						add(SeverityCritical, path+".skipTLSVerify", "TLS verification is disabled for cluster %s and no caData or caFile is set", cluster.Name)
+					} else {
+						add(SeverityWarning, path+".skipTLSVerify", "TLS verification is disabled for cluster %s even though a CA is configured", cluster.Name)
+					}
+				}
+				if strings.HasPrefix(cluster.Url, "http://") {
+					add(SeverityCritical, path+".url", "cluster %s API server is reached over plaintext HTTP", cluster.Name)
+				}
+			}
+		}
+	}
+
+	if config.Permission.Enabled && len(config.Permission.Rbac.Admin.Users) == 0 && len(config.Permission.Rbac.SuperAdmin.Users) == 0 {
+		add(SeverityCritical, "permission.rbac", "permissions are enabled but no admin or super admin users are configured")
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Severity != findings[j].Severity {
+			return findings[i].Severity > findings[j].Severity
+		}
+		return findings[i].Path < findings[j].Path
+	})
+	return findings
+}
+
+// auditExitCode returns the exit code for the highest severity found, strict
+// mode fails on warnings and above
+func auditExitCode(highest Severity, strict bool) int {
+	if strict && highest >= SeverityWarning {
+		return 2
+	}
+	return 0
+}
+
+// printFindings writes the audit report and returns the highest severity found
+func printFindings(w io.Writer, findings []Finding) Severity {
+	highest := SeverityInfo
+	if len(findings) == 0 {
+		return highest
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Security Audit")
+	fmt.Fprintln(w, "==============")
+	for _, finding := range findings {
+		fmt.Fprintf(w, "[%s] %s: %s\n", finding.Severity, finding.Path, finding.Message)
+		if finding.Severity > highest {
+			highest = finding.Severity
+		}
+	}
+	return highest
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `Usage: backstage-config-generator [command] [flags]
+
+Commands:
+  (none)          Run the wizard and write the configuration
+  presets         List the presets
+  diff            Compare two configurations
+  migrate         Migrate a configuration to newer plugin releases
+  doctor          Check a configuration against the installed plugins
+  preview-ingest  Preview the entities the Kubernetes Ingestor creates from manifests
+  schema          Write the JSON Schema of the generated configuration
+  explain         Show the documentation of a config key
+
+Run a command with -h to list its flags.
+`
+
+// newFlagSet returns the flag set of a command, usage describes its arguments
+func newFlagSet(name, usage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: backstage-config-generator %s\n", usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// addDefaultsFlag adds the --defaults flag of the commands using the prompt defaults
+func addDefaultsFlag(flags *flag.FlagSet) *string {
+	return flags.String("defaults", "", "YAML profile overriding the built-in prompt defaults")
+}
+
+func mustLoadDefaults(path string) {
+	var err error
+	defaults, err = loadDefaults(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading defaults: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// outputOptions control how an existing output is replaced
+type outputOptions struct {
+	path                  string
+	diff                  bool
+	backup                bool
+	allowPlaintextSecrets bool
+}
+
+// runWizard asks the questions of every section and writes what --emit selects
+func runWizard(args []string) {
+	flags := newFlagSet("", "[flags]")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		fmt.Fprintln(flags.Output(), "\nWizard flags:")
+		flags.PrintDefaults()
+	}
+	var output outputOptions
+	flags.StringVar(&output.path, "output", "", "Output file path (defaults to stdout), or the output directory, required for --emit compose")
+	kubeconfigPath := flags.String("kubeconfig", "", "Kubeconfig file to import Kubernetes clusters from")
+	production := flags.Bool("production", false, "Audit the configuration for a production deployment")
+	strict := flags.Bool("strict", false, "Exit with a non-zero code when the security audit reports warnings")
+	defaultsFile := addDefaultsFlag(flags)
+	answersFile := flags.String("answers", "", "YAML file with answers by section and question ID, unanswered questions are asked")
+	presetName := flags.String("preset", "", "Preset selecting the sections to configure (see 'presets')")
+	plain := flags.Bool("plain", false, "Use plain line prompts even when running in a terminal")
+	flags.BoolVar(&output.diff, "diff", false, "Show the changes against the existing output file and confirm before writing")
+	flags.BoolVar(&output.backup, "backup", false, "Keep a timestamped copy of the existing output file before overwriting it, required to overwrite a compose stack")
+	flags.BoolVar(&output.allowPlaintextSecrets, "allow-plaintext-secrets", false, "Allow writing plaintext secrets into a git-tracked file")
+	emit := flags.String("emit", "config", "What to generate: config (app-config.yaml), k8s (deployment manifests) helm (values.yaml for the Backstage chart) or compose (docker-compose stack)")
+	resolveSecrets := flags.Bool("resolve-secrets", false, "Resolve env://, file:// and vault:// secret references instead of writing $env/$file includes")
+	schemaURL := flags.String("schema", "", "Add a yaml-language-server header validating the generated config against the JSON Schema at this URL or path (see 'schema')")
+	flags.Parse(args)
+	if flags.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", flags.Arg(0))
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	mustLoadDefaults(*defaultsFile)
+	var err error
+	if *answersFile != "" {
+		providedAnswers, err = loadAnswers(*answersFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading answers: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *presetName != "" {
+		activePreset, err = lookupPreset(*presetName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	config := askConfig(configSections(*kubeconfigPath), *plain)
+	if *resolveSecrets {
+		if err := resolveSecretRefs(config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if code := auditExitCode(printFindings(os.Stderr, auditConfig(config, *production)), *strict); code != 0 {
+		fmt.Fprintln(os.Stderr, "Security audit failed in strict mode, configuration not written")
+		os.Exit(code)
+	}
+
+	if *emit == "compose" {
+		writeCompose(config, output)
+		return
+	}
+
+	var yamlData []byte
+	switch *emit {
+	case "config":
+		yamlData, err = marshalConfig(config)
+		if *schemaURL != "" {
+			yamlData = append([]byte(schemaHeader(*schemaURL)), yamlData...)
+		}
+	case "k8s":
+		yamlData, err = renderK8sManifests(config)
+	case "helm":
+		yamlData, err = renderHelmValues(config)
+	default:
+		err = fmt.Errorf("unknown --emit target %q", *emit)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshaling YAML: %v\n", err)
+		os.Exit(1)
+	}
+	if output.path == "" {
+		fmt.Println(string(yamlData))
+		return
+	}
+	writeConfig(config, yamlData, output, *emit == "config")
+}
+
+// askConfig runs the sections with plain prompts, or in the TUI when the
+// terminal supports it
+func askConfig(sections []Section, plain bool) *Config {
+	var config Config
+	fmt.Println("Answer ? at any prompt to show the documentation of the setting.")
+	if plain || !tuiAvailable() {
+		for _, section := range sections {
+			if err := section.Run(&config); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		return &config
+	}
+
+	tuiEnabled = true
+	written, err := runTUI(&config, sections)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !written {
+		fmt.Fprintln(os.Stderr, "Aborted, configuration not written")
+		os.Exit(1)
+	}
+	return &config
+}
+
+// refusePlaintextSecrets reports whether secrets would be written in plaintext
+// into a git-tracked file, and lists them on w when they would
+func refusePlaintextSecrets(w io.Writer, path string, secrets []string, output outputOptions) bool {
+	if len(secrets) == 0 || output.allowPlaintextSecrets || !isGitTracked(path) {
+		return false
+	}
+	fmt.Fprintf(w, "Refusing to write plaintext secrets into git-tracked file %s:\n", path)
+	for _, secret := range secrets {
+		fmt.Fprintf(w, "  %s\n", secret)
+	}
+	fmt.Fprintln(w, "Use ${VAR} placeholders or pass --allow-plaintext-secrets")
+	return true
+}
+
+func plaintextSecretPaths(secrets []SecretField) []string {
+	var paths []string
+	for _, secret := range plaintextSecrets(secrets) {
+		paths = append(paths, secret.Path)
+	}
+	return paths
+}
+
+// writeConfig writes a single generated file, showing the changes to the
+// existing config first with --diff
+func writeConfig(config *Config, yamlData []byte, output outputOptions, diffable bool) {
+	secrets := secretFields(config)
+	if refusePlaintextSecrets(os.Stderr, output.path, plaintextSecretPaths(secrets), output) {
+		os.Exit(1)
+	}
+
+	if _, err := os.Stat(output.path); err == nil {
+		if output.diff && diffable {
+			existing, existingConfig, err := loadTree(output.path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading existing configuration: %v\n", err)
+				os.Exit(1)
+			}
+			var generated interface{}
+			if err := yaml.Unmarshal(yamlData, &generated); err != nil {
+				fmt.Fprintf(os.Stderr, "Error comparing configuration: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("")
+			fmt.Printf("Changes to %s\n", output.path)
+			fmt.Println("==========================")
+			printDiff(os.Stdout, diffTrees("", existing, generated), secretPaths(existingConfig, config))
+			if !promptRepeat(fmt.Sprintf("Write changes to %s?", output.path), false) {
+				fmt.Println("Configuration not written")
+				return
+			}
+		}
+		if output.backup {
+			backupPath, err := backupFile(output.path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing backup: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Existing configuration saved to %s\n", backupPath)
+		}
+	}
+	perm := os.FileMode(0644)
+	if len(secrets) > 0 {
+		perm = 0600
+	}
+	if err := writeFileAtomic(output.path, yamlData, perm); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Configuration written to %s\n", output.path)
+}
+
+// writeCompose writes the compose stack into the --output directory, existing
+// files are only replaced with --backup
+func writeCompose(config *Config, output outputOptions) {
+	dir := output.path
+	if dir == "" {
+		fmt.Fprintln(os.Stderr, "Error: --emit compose writes several files, pass the directory to write them to with --output")
+		os.Exit(1)
+	}
+	buildContext, err := composeBuildContext(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if buildContext == "" {
+		fmt.Fprintf(os.Stderr, "Warning: no backstage.json in %s or above it, the stack runs %s without building it\n", dir, defaults.Deploy.Image)
+	}
+	stack, err := renderComposeStack(config, buildContext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshaling YAML: %v\n", err)
+		os.Exit(1)
+	}
+	for _, name := range stack.Names {
+		if refusePlaintextSecrets(os.Stderr, filepath.Join(dir, name), stack.Secrets[name], output) {
+			os.Exit(1)
+		}
+	}
+	if existing := existingComposeFiles(dir, stack); len(existing) > 0 {
+		if !output.backup {
+			fmt.Fprintf(os.Stderr, "Refusing to overwrite %s in %s, pass --backup to keep a copy of them and write the stack\n", strings.Join(existing, ", "), dir)
+			os.Exit(1)
+		}
+		if output.diff {
+			if err := printComposeDiff(os.Stdout, dir, stack, existing); err != nil {
+				fmt.Fprintf(os.Stderr, "Error comparing compose stack: %v\n", err)
+				os.Exit(1)
+			}
+			if !promptRepeat(fmt.Sprintf("Write changes to %s?", dir), false) {
+				fmt.Println("Compose stack not written")
+				return
+			}
+		}
+		for _, name := range existing {
+			backupPath, err := backupFile(filepath.Join(dir, name))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing backup: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("Existing %s saved to %s\n", name, backupPath)
+		}
+	}
+	if err := writeComposeStack(dir, stack); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Compose stack written to %s (%s)\n", dir, strings.Join(stack.Names, ", "))
+}
+
+func runPresets(args []string) {
+	flags := newFlagSet("presets", "presets")
+	flags.Parse(args)
+	listPresets(os.Stdout)
+}
+
+func runDiff(args []string) {
+	flags := newFlagSet("diff", "diff <existing.yaml> <generated.yaml>")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	if err := diffFiles(os.Stdout, flags.Arg(0), flags.Arg(1)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runMigrate(args []string) {
+	flags := newFlagSet("migrate", "migrate --from <version> [--to <version>] [--backstage-json backstage.json] [--output file] <app-config.yaml>")
+	from := flags.String("from", "", "Backstage version the config was written for")
+	to := flags.String("to", "", "Backstage version to migrate the config to (defaults to the version in backstage.json)")
+	backstageJSON := flags.String("backstage-json", "backstage.json", "backstage.json of the app, used to find its current Backstage version")
+	output := flags.String("output", "", "Output file path for the migrated config (defaults to stdout)")
+	allowPlaintextSecrets := flags.Bool("allow-plaintext-secrets", false, "Allow writing plaintext secrets into a git-tracked file")
+	flags.Parse(args)
+	if *from == "" || flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	if *to == "" {
+		version, err := backstageVersion(*backstageJSON)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the Backstage version, set --to: %v\n", err)
+			os.Exit(1)
+		}
+		*to = version
+	}
+	pending, err := pendingMigrations(*from, *to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading configuration: %v\n", err)
+		os.Exit(1)
+	}
+	migrated, report, err := migrateConfig(data, pending)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error migrating %s: %v\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		// Keep stdout for the config so it can be redirected
+		printMigrationReport(os.Stderr, *from, *to, pending, report)
+		fmt.Print(string(migrated))
+		return
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(migrated, &config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the migrated configuration: %v\n", err)
+		os.Exit(1)
+	}
+	secrets := secretFields(&config)
+	if refusePlaintextSecrets(os.Stderr, *output, plaintextSecretPaths(secrets), outputOptions{allowPlaintextSecrets: *allowPlaintextSecrets}) {
+		os.Exit(1)
+	}
+
+	printMigrationReport(os.Stdout, *from, *to, pending, report)
+	perm := os.FileMode(0644)
+	if len(secrets) > 0 {
+		perm = 0600
+	}
+	if err := writeFileAtomic(*output, migrated, perm); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Migrated config written to %s\n", *output)
+}
+
+func runDoctor(args []string) {
+	flags := newFlagSet("doctor", "doctor [--app-dir dir] [--config app-config.yaml] [--strict]")
+	appDir := flags.String("app-dir", ".", "Root of the Backstage app, holding packages/app and packages/backend")
+	configFile := flags.String("config", "", "Config to check against the installed plugins (defaults to app-config.yaml in --app-dir)")
+	strict := flags.Bool("strict", false, "Exit with a non-zero code when warnings are reported")
+	flags.Parse(args)
+	if *configFile == "" {
+		*configFile = filepath.Join(*appDir, "app-config.yaml")
+	}
+
+	plugins, err := findInstalledPlugins(*appDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the installed plugins: %v\n", err)
+		os.Exit(1)
+	}
+	tree, _, err := loadTree(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading configuration: %v\n", err)
+		os.Exit(1)
+	}
+	findings := checkPlugins(tree, plugins)
+	printDoctor(os.Stdout, plugins, findings)
+	if len(findings) > 0 && *strict {
+		os.Exit(1)
+	}
+}
+
+func runPreviewIngest(args []string) {
+	flags := newFlagSet("preview-ingest", "preview-ingest --manifests <dir> [--config app-config.yaml] [--cluster name]")
+	manifests := flags.String("manifests", "", "Manifest file or directory of YAML manifests to preview")
+	configFile := flags.String("config", "", "Generated app-config to take the kubernetesIngestor rules from (defaults to the wizard defaults)")
+	cluster := flags.String("cluster", "local", "Cluster name the manifests are treated as coming from")
+	defaultsFile := addDefaultsFlag(flags)
+	flags.Parse(args)
+	if *manifests == "" {
+		flags.Usage()
+		os.Exit(1)
+	}
+	mustLoadDefaults(*defaultsFile)
+
+	ingestor := defaultIngestorConfig()
+	if *configFile != "" {
+		_, existing, err := loadTree(*configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading configuration: %v\n", err)
+			os.Exit(1)
+		}
+		if existing.KubernetesIngestor == nil {
+			fmt.Fprintf(os.Stderr, "Error: %s has no kubernetesIngestor section\n", *configFile)
+			os.Exit(1)
+		}
+		ingestor = existing.KubernetesIngestor
+	}
+	objects, files, err := readManifestDir(*manifests)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading manifests: %v\n", err)
+		os.Exit(1)
+	}
+	printIngestPreview(os.Stdout, previewIngestion(ingestor, objects, files, *cluster))
+}
+
+func runSchema(args []string) {
+	flags := newFlagSet("schema", "schema [--output file]")
+	output := flags.String("output", "", "Output file path for the schema (defaults to stdout)")
+	defaultsFile := addDefaultsFlag(flags)
+	flags.Parse(args)
+	mustLoadDefaults(*defaultsFile)
+
+	schema, err := renderSchema()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating schema: %v\n", err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		fmt.Print(string(schema))
+		return
+	}
+	if err := writeFileAtomic(*output, schema, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Schema written to %s\n", *output)
+}
+
+func runExplain(args []string) {
+	flags := newFlagSet("explain", "explain [key]")
+	defaultsFile := addDefaultsFlag(flags)
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+	mustLoadDefaults(*defaultsFile)
+
+	if err := explain(os.Stdout, flags.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printUsage lists the commands
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, usage)
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeFile covers the parts of the compose specification the generator emits
+type ComposeFile struct {
+	Services map[string]ComposeService `yaml:"services"`
+	Volumes  map[string]struct{}       `yaml:"volumes,omitempty"`
+}
+
+type ComposeService struct {
+	Image       string                      `yaml:"image"`
+	Build       *ComposeBuild               `yaml:"build,omitempty"`
+	Command     []string                    `yaml:"command,omitempty"`
+	Ports       []string                    `yaml:"ports,omitempty"`
+	EnvFile     []string                    `yaml:"env_file,omitempty"`
+	Environment map[string]string           `yaml:"environment,omitempty"`
+	Volumes     []string                    `yaml:"volumes,omitempty"`
+	DependsOn   map[string]ComposeDependsOn `yaml:"depends_on,omitempty"`
+	Healthcheck *ComposeHealthcheck         `yaml:"healthcheck,omitempty"`
+}
+
+type ComposeBuild struct {
+	Context    string `yaml:"context"`
+	Dockerfile string `yaml:"dockerfile"`
+}
+
+type ComposeDependsOn struct {
+	Condition string `yaml:"condition"`
+}
+
+type ComposeHealthcheck struct {
+	Test     []string `yaml:"test"`
+	Interval string   `yaml:"interval"`
+	Retries  int      `yaml:"retries"`
+}
+
+const (
+	composeFileName      = "docker-compose.yaml"
+	composeEnvFileName   = ".env"
+	composeAppConfigName = "app-config.yaml"
+	composePostgresImage = "postgres:16"
+)
+
+// ComposeStack holds the files of a compose stack by name, in the order they are
+// written, along with the plaintext secrets each of them holds
+type ComposeStack struct {
+	Names   []string
+	Files   map[string][]byte
+	Secrets map[string][]string
+}
+
+func (s *ComposeStack) add(name string, data []byte, secrets []string) {
+	s.Names = append(s.Names, name)
+	s.Files[name] = data
+	if len(secrets) > 0 {
+		s.Secrets[name] = secrets
+	}
+}
+
+// composeBuildHeader heads a docker-compose.yaml building the image
+const composeBuildHeader = `# Run yarn install, yarn tsc and yarn build:backend in the app root before
+# docker compose up --build, the Dockerfile copies packages/backend/dist/*.tar.gz
+`
+
+// composeBuildContext returns the build context of a stack written to dir, which
+// compose resolves against dir. deploy.buildContext is relative to the app root,
+// the nearest directory from dir up holding backstage.json. It returns "" when
+// there is none.
+func composeBuildContext(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	root := dir
+	for {
+		if _, err := os.Stat(filepath.Join(root, "backstage.json")); err == nil {
+			break
+		}
+		parent := filepath.Dir(root)
+		if parent == root {
+			return "", nil
+		}
+		root = parent
+	}
+	context := defaults.Deploy.BuildContext
+	if !filepath.IsAbs(context) {
+		context = filepath.Join(root, context)
+	}
+	if context, err = filepath.Rel(dir, context); err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(context), nil
+}
+
+// renderComposeStack renders a docker-compose.yaml running the image built from the
+// repo Dockerfile with the generated app-config mounted read-only, an .env file for
+// the secrets and a Postgres service when the database calls for it. Without a
+// build context the image is used as is.
+func renderComposeStack(config *Config, buildContext string) (*ComposeStack, error) {
+	deployConfig, err := cloneConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	port := backendPort(config)
+	// The backend serves the frontend bundle, so both are reached on the published port
+	baseUrl := fmt.Sprintf("http://localhost:%d", port)
+	deployConfig.App.BaseUrl = baseUrl
+	deployConfig.Backend.BaseUrl = baseUrl
+	if deployConfig.Backend.CORS.Origin != "" && deployConfig.Backend.CORS.Origin != "*" {
+		deployConfig.Backend.CORS.Origin = baseUrl
+	}
+
+	postgres := deployConfig.Backend.Database.Client == "pg"
+	if postgres {
+		connection := &deployConfig.Backend.Database.Connection
+		connection.Host = "postgres"
+		connection.Port = "5432"
+		if connection.User == "" {
+			connection.User = "backstage"
+		}
+		if connection.Password == "" {
+			connection.Password = "${POSTGRES_PASSWORD}"
+		}
+	}
+
+	env := extractSecrets(deployConfig)
+	appConfig, err := marshalConfig(deployConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	configPath := k8sConfigDir + "/" + composeAppConfigName
+	backstage := ComposeService{
+		Image:   defaults.Deploy.Image,
+		Command: []string{"node", "packages/backend", "--config", configPath},
+		Ports:   []string{fmt.Sprintf("%d:%d", port, port)},
+		Volumes: []string{"./" + composeAppConfigName + ":" + configPath + ":ro"},
+	}
+	if buildContext != "" {
+		backstage.Build = &ComposeBuild{Context: buildContext, Dockerfile: defaults.Deploy.Dockerfile}
+	}
+	if len(env) > 0 {
+		backstage.EnvFile = []string{composeEnvFileName}
+	}
+
+	compose := ComposeFile{Services: map[string]ComposeService{"backstage": backstage}}
+	if postgres {
+		connection := deployConfig.Backend.Database.Connection
+		passwordVar := strings.TrimSuffix(strings.TrimPrefix(connection.Password, "${"), "}")
+		compose.Services["postgres"] = ComposeService{
+			Image: composePostgresImage,
+			Environment: map[string]string{
+				"POSTGRES_USER":     connection.User,
+				"POSTGRES_PASSWORD": fmt.Sprintf("${%s}", passwordVar),
+			},
+			Volumes: []string{"postgres-data:/var/lib/postgresql/data"},
+			Healthcheck: &ComposeHealthcheck{
+				Test:     []string{"CMD-SHELL", "pg_isready -U " + connection.User},
+				Interval: "5s",
+				Retries:  10,
+			},
+		}
+		compose.Volumes = map[string]struct{}{"postgres-data": {}}
+		backstage.DependsOn = map[string]ComposeDependsOn{"postgres": {Condition: "service_healthy"}}
+		compose.Services["backstage"] = backstage
+	}
+
+	var buf bytes.Buffer
+	if buildContext != "" {
+		buf.WriteString(composeBuildHeader)
+	}
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(compose); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	var appConfigSecrets []string
+	for _, secret := range plaintextSecrets(secretFields(deployConfig)) {
+		appConfigSecrets = append(appConfigSecrets, secret.Path)
+	}
+	stack := &ComposeStack{Files: make(map[string][]byte), Secrets: make(map[string][]string)}
+	stack.add(composeFileName, buf.Bytes(), nil)
+	stack.add(composeAppConfigName, appConfig, appConfigSecrets)
+	if len(env) > 0 {
+		var envFile bytes.Buffer
+		var envSecrets []string
+		for _, name := range sortedKeys(env) {
+			fmt.Fprintf(&envFile, "%s=%s\n", name, env[name])
+			if env[name] != "" {
+				envSecrets = append(envSecrets, name)
+			}
+		}
+		stack.add(composeEnvFileName, envFile.Bytes(), envSecrets)
+		warnEmptyEnvFile(env)
+	}
+	return stack, nil
+}
+
+// warnEmptyEnvFile lists the variables that have to be filled in before starting the stack
+func warnEmptyEnvFile(env map[string]string) {
+	var missing []string
+	for _, name := range sortedKeys(env) {
+		if env[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Fill in the values of %s in %s before starting the stack\n", strings.Join(missing, ", "), composeEnvFileName)
+	}
+}
+
+// existingComposeFiles returns the names of the stack files already in dir
+func existingComposeFiles(dir string, stack *ComposeStack) []string {
+	var existing []string
+	for _, name := range stack.Names {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			existing = append(existing, name)
+		}
+	}
+	return existing
+}
+
+// composeTree parses a stack file for diffing, the .env file becomes a mapping
+// of its variables
+func composeTree(name string, data []byte) (interface{}, error) {
+	if name != composeEnvFileName {
+		var tree interface{}
+		err := yaml.Unmarshal(data, &tree)
+		return tree, err
+	}
+	tree := make(map[string]interface{})
+	for _, line := range strings.Split(string(data), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			tree[strings.TrimSpace(key)] = value
+		}
+	}
+	return tree, nil
+}
+
+// printComposeDiff shows the changes to the given stack files in dir, the
+// values of the .env file and the secrets of the app-config are masked
+func printComposeDiff(w io.Writer, dir string, stack *ComposeStack, names []string) error {
+	for _, name := range names {
+		existingData, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		existing, err := composeTree(name, existingData)
+		if err != nil {
+			return fmt.Errorf("parsing %s: %w", filepath.Join(dir, name), err)
+		}
+		generated, err := composeTree(name, stack.Files[name])
+		if err != nil {
+			return err
+		}
+
+		secrets := make(map[string]bool)
+		switch name {
+		case composeEnvFileName:
+			for _, tree := range []interface{}{existing, generated} {
+				for key := range tree.(map[string]interface{}) {
+					secrets[key] = true
+				}
+			}
+		case composeAppConfigName:
+			var existingConfig, generatedConfig Config
+			yaml.Unmarshal(existingData, &existingConfig)
+			yaml.Unmarshal(stack.Files[name], &generatedConfig)
+			secrets = secretPaths(&existingConfig, &generatedConfig)
+		}
+
+		fmt.Fprintln(w, "")
+		fmt.Fprintf(w, "Changes to %s\n", filepath.Join(dir, name))
+		fmt.Fprintln(w, "==========================")
+		printDiff(w, diffTrees("", existing, generated), secrets)
+	}
+	return nil
+}
+
+// writeComposeStack writes the stack files into dir, keeping the .env file private
+func writeComposeStack(dir string, stack *ComposeStack) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, name := range stack.Names {
+		perm := os.FileMode(0644)
+		if name == composeEnvFileName {
+			perm = 0600
+		}
+		if err := writeFileAtomic(filepath.Join(dir, name), stack.Files[name], perm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type DiffKind int
+
+const (
+	DiffAdded DiffKind = iota
+	DiffRemoved
+	DiffChanged
+)
+
+// DiffEntry is a single changed leaf value, addressed by its key path
+type DiffEntry struct {
+	Kind DiffKind
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// secretPaths returns the key paths of the secret fields in the given configs,
+// these values are masked in diff output
+func secretPaths(configs ...*Config) map[string]bool {
+	paths := make(map[string]bool)
+	for _, config := range configs {
+		for _, secret := range secretFields(config) {
+			paths[secret.Path] = true
+		}
+	}
+	return paths
+}
+
+// secretKeyPattern matches the keys of secrets the Config types do not model,
+// such as integration tokens and provider credentials
+var secretKeyPattern = regexp.MustCompile(`(?i)(token|secret|password|key)$`)
+
+// isSecretKey reports whether the value at path looks like a secret from its
+// key alone. Proxy headers are all masked, they often carry credentials.
+func isSecretKey(path string) bool {
+	segments := strings.Split(path, ".")
+	for _, segment := range segments[:len(segments)-1] {
+		if segment == "headers" {
+			return true
+		}
+	}
+	key := segments[len(segments)-1]
+	if i := strings.Index(key, "["); i >= 0 {
+		key = key[:i]
+	}
+	return secretKeyPattern.MatchString(key) || untaggedSecretKeys[key]
+}
+
+// loadTree parses a config file both as a generic tree and as a Config, the
+// latter is used to find its secret fields
+func loadTree(path string) (interface{}, *Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var tree interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	// Hand-written configs may not match the Config types everywhere. The decoder
+	// still fills in every field it can, which is enough to find the secrets.
+	var config Config
+	yaml.Unmarshal(data, &config)
+	return tree, &config, nil
+}
+
+// diffTrees compares two YAML trees and returns the differences at leaf level
+func diffTrees(path string, old, updated interface{}) []DiffEntry {
+	oldMap, oldIsMap := old.(map[string]interface{})
+	updatedMap, updatedIsMap := updated.(map[string]interface{})
+	if oldIsMap && updatedIsMap {
+		keys := make(map[string]bool)
+		for k := range oldMap {
+			keys[k] = true
+		}
+		for k := range updatedMap {
+			keys[k] = true
+		}
+		var sorted []string
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+
+		var entries []DiffEntry
+		for _, k := range sorted {
+			oldVal, inOld := oldMap[k]
+			updatedVal, inUpdated := updatedMap[k]
+			childPath := joinPath(path, k)
+			switch {
+			case !inOld:
+				entries = append(entries, leaves(DiffAdded, childPath, updatedVal)...)
+			case !inUpdated:
+				entries = append(entries, leaves(DiffRemoved, childPath, oldVal)...)
+			default:
+				entries = append(entries, diffTrees(childPath, oldVal, updatedVal)...)
+			}
+		}
+		return entries
+	}
+
+	oldList, oldIsList := old.([]interface{})
+	updatedList, updatedIsList := updated.([]interface{})
+	if oldIsList && updatedIsList {
+		var entries []DiffEntry
+		for i := 0; i < len(oldList) || i < len(updatedList); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(oldList):
+				entries = append(entries, leaves(DiffAdded, childPath, updatedList[i])...)
+			case i >= len(updatedList):
+				entries = append(entries, leaves(DiffRemoved, childPath, oldList[i])...)
+			default:
+				entries = append(entries, diffTrees(childPath, oldList[i], updatedList[i])...)
+			}
+		}
+		return entries
+	}
+
+	if reflect.DeepEqual(old, updated) {
+		return nil
+	}
+	return []DiffEntry{{Kind: DiffChanged, Path: path, Old: old, New: updated}}
+}
+
+// leaves flattens an added or removed subtree into one entry per scalar value
+func leaves(kind DiffKind, path string, v interface{}) []DiffEntry {
+	var entries []DiffEntry
+	switch v := v.(type) {
+	case map[string]interface{}:
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			entries = append(entries, leaves(kind, joinPath(path, k), v[k])...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			entries = append(entries, leaves(kind, fmt.Sprintf("%s[%d]", path, i), item)...)
+		}
+	default:
+		entry := DiffEntry{Kind: kind, Path: path}
+		if kind == DiffAdded {
+			entry.New = v
+		} else {
+			entry.Old = v
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func formatDiffValue(v interface{}, secret bool) string {
+	if s, ok := v.(string); ok && secret && s != "" && !isPlaceholder(s) {
+		return "********"
+	}
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func printDiff(w io.Writer, entries []DiffEntry, secrets map[string]bool) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	for _, entry := range entries {
+		secret := secrets[entry.Path] || isSecretKey(entry.Path)
+		switch entry.Kind {
+		case DiffAdded:
+			fmt.Fprintf(w, "+ %s: %s\n", entry.Path, formatDiffValue(entry.New, secret))
+		case DiffRemoved:
+			fmt.Fprintf(w, "- %s: %s\n", entry.Path, formatDiffValue(entry.Old, secret))
+		case DiffChanged:
+			fmt.Fprintf(w, "~ %s: %s -> %s\n", entry.Path, formatDiffValue(entry.Old, secret), formatDiffValue(entry.New, secret))
+		}
+	}
+}
+
+// diffFiles implements the diff subcommand
+func diffFiles(w io.Writer, oldPath, newPath string) error {
+	old, oldConfig, err := loadTree(oldPath)
+	if err != nil {
+		return err
+	}
+	updated, updatedConfig, err := loadTree(newPath)
+	if err != nil {
+		return err
+	}
+	printDiff(w, diffTrees("", old, updated), secretPaths(oldConfig, updatedConfig))
+	return nil
+}
+
+// backupFile copies path to a timestamped sibling and returns the backup path,
+// backups made within the same second get a counter
+func backupFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	stamp := time.Now().Format("20060102-150405")
+	for n := 0; ; n++ {
+		backup := fmt.Sprintf("%s.%s.bak", path, stamp)
+		if n > 0 {
+			backup = fmt.Sprintf("%s.%s-%d.bak", path, stamp, n)
+		}
+		file, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := file.Write(data); err != nil {
+			file.Close()
+			return "", err
+		}
+		return backup, file.Close()
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// PluginConfig links a config section the generator writes to the TeraSky
+// plugins reading it, the section is used when any of them is installed
+type PluginConfig struct {
+	Key      string
+	Packages []string
+}
+
+var pluginConfigs = []PluginConfig{
+	{Key: "kubernetesIngestor", Packages: []string{"@terasky/backstage-plugin-kubernetes-ingestor"}},
+	{Key: "crossplane", Packages: []string{"@terasky/backstage-plugin-crossplane-resources-frontend"}},
+	{Key: "kyverno", Packages: []string{"@terasky/backstage-plugin-kyverno-policy-reports"}},
+	{Key: "scaleops", Packages: []string{"@terasky/backstage-plugin-scaleops-frontend"}},
+	{Key: "devpod", Packages: []string{"@terasky/backstage-plugin-devpod"}},
+	{Key: "vcfAutomation", Packages: []string{"@terasky/backstage-plugin-vcf-automation-ingestor", "@terasky/backstage-plugin-vcf-automation-backend"}},
+}
+
+// InstalledPlugins are the @terasky packages found in the app's workspaces
+type InstalledPlugins struct {
+	// Frontend are the dependencies of packages/app
+	Frontend map[string]bool
+	// Backend are the dependencies of packages/backend
+	Backend map[string]bool
+	// Registered are the packages added in packages/backend/src/index.ts
+	Registered map[string]bool
+}
+
+// Installed tells whether a plugin is in use, backend plugins also have to be
+// added to the backend
+func (p InstalledPlugins) Installed(name string) bool {
+	return p.Frontend[name] || (p.Backend[name] && p.Registered[name])
+}
+
+// backendAddPattern matches the backend.add calls of a whole index.ts, including
+// the ones prettier wraps over several lines, but not commented out ones
+var backendAddPattern = regexp.MustCompile(`(?m)^[ \t]*backend\.add\(\s*import\(\s*['"](@terasky/[^'"/]+)[^'"]*['"]\s*\)`)
+
+// packageDependencies returns the @terasky dependencies of a package.json
+func packageDependencies(path string) (map[string]bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	dependencies := make(map[string]bool)
+	for name := range manifest.Dependencies {
+		if strings.HasPrefix(name, "@terasky/") {
+			dependencies[name] = true
+		}
+	}
+	return dependencies, nil
+}
+
+// findInstalledPlugins reads the package.json files of the app and backend
+// packages of the Backstage app at dir, and the plugins the backend adds
+func findInstalledPlugins(dir string) (InstalledPlugins, error) {
+	var plugins InstalledPlugins
+	var err error
+	if plugins.Frontend, err = packageDependencies(filepath.Join(dir, "packages", "app", "package.json")); err != nil {
+		return plugins, err
+	}
+	if plugins.Backend, err = packageDependencies(filepath.Join(dir, "packages", "backend", "package.json")); err != nil {
+		return plugins, err
+	}
+	index, err := os.ReadFile(filepath.Join(dir, "packages", "backend", "src", "index.ts"))
+	if err != nil {
+		return plugins, err
+	}
+	plugins.Registered = make(map[string]bool)
+	for _, match := range backendAddPattern.FindAllStringSubmatch(string(index), -1) {
+		plugins.Registered[match[1]] = true
+	}
+	return plugins, nil
+}
+
+// checkPlugins compares the sections of a config with the installed plugins
+func checkPlugins(tree interface{}, plugins InstalledPlugins) []Finding {
+	var findings []Finding
+	add := func(path, format string, args ...interface{}) {
+		findings = append(findings, Finding{Severity: SeverityWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var unregistered []string
+	for name := range plugins.Backend {
+		if !plugins.Registered[name] {
+			unregistered = append(unregistered, name)
+		}
+	}
+	sort.Strings(unregistered)
+	for _, name := range unregistered {
+		add("packages/backend/src/index.ts", "%s is a backend dependency but is not added with backend.add", name)
+	}
+
+	root, _ := tree.(map[string]interface{})
+	for _, plugin := range pluginConfigs {
+		var installed []string
+		for _, name := range plugin.Packages {
+			if plugins.Installed(name) {
+				installed = append(installed, name)
+			}
+		}
+		// Sections the generator skipped can be written as null
+		configured := root[plugin.Key] != nil
+		switch {
+		case configured && len(installed) == 0:
+			add(plugin.Key, "configures %s, which is not installed", strings.Join(plugin.Packages, " or "))
+		case !configured && len(installed) == 1:
+			add(plugin.Key, "%s is installed but the config has no %s section", installed[0], plugin.Key)
+		case !configured && len(installed) > 1:
+			add(plugin.Key, "%s are installed but the config has no %s section", strings.Join(installed, " and "), plugin.Key)
+		}
+	}
+	return findings
+}
+
+// printDoctor lists the installed plugins and the findings of checkPlugins
+func printDoctor(w io.Writer, plugins InstalledPlugins, findings []Finding) {
+	seen := make(map[string]bool)
+	var installed []string
+	for _, names := range []map[string]bool{plugins.Frontend, plugins.Backend} {
+		for name := range names {
+			if plugins.Installed(name) && !seen[name] {
+				seen[name] = true
+				installed = append(installed, name)
+			}
+		}
+	}
+	sort.Strings(installed)
+	fmt.Fprintln(w, "Installed TeraSky plugins")
+	fmt.Fprintln(w, "=========================")
+	if len(installed) == 0 {
+		fmt.Fprintln(w, "  none")
+	}
+	for _, name := range installed {
+		fmt.Fprintf(w, "  %s\n", name)
+	}
+
+	fmt.Fprintln(w, "")
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "The config matches the installed plugins")
+		return
+	}
+	for _, finding := range findings {
+		fmt.Fprintf(w, "[%s] %s: %s\n", finding.Severity, finding.Path, finding.Message)
+	}
+}
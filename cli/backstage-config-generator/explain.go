@@ -0,0 +1,253 @@
+package main
+
+//go:generate go run helpgen.go
+
+import (
+	_ "embed"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyHelp is the documentation of a config key extracted from the docs by helpgen.go
+type KeyHelp struct {
+	Description       string            `yaml:"description"`
+	Values            []string          `yaml:"values"`
+	ValueDescriptions map[string]string `yaml:"valueDescriptions"`
+	Example           string            `yaml:"example"`
+	Sources           []string          `yaml:"sources"`
+}
+
+//go:embed help.yaml
+var helpData []byte
+
+var keyHelp map[string]KeyHelp
+
+func loadKeyHelp() map[string]KeyHelp {
+	if keyHelp == nil {
+		// help.yaml is generated and embedded, it only fails to parse if it was edited by hand
+		if err := yaml.Unmarshal(helpData, &keyHelp); err != nil {
+			panic(fmt.Sprintf("embedded help.yaml: %v", err))
+		}
+	}
+	return keyHelp
+}
+
+var listIndexPattern = regexp.MustCompile(`\[[^]]*\]`)
+
+// normalizeKey drops list indexes, since all items of a list share their help
+func normalizeKey(key string) string {
+	return listIndexPattern.ReplaceAllString(strings.TrimSpace(key), "")
+}
+
+// keyMatches compares keys segment by segment, "*" stands for map keys such as proxy paths
+func keyMatches(pattern, key string) bool {
+	patternParts, keyParts := strings.Split(pattern, "."), strings.Split(key, ".")
+	if len(patternParts) != len(keyParts) {
+		return false
+	}
+	for i := range patternParts {
+		if patternParts[i] != keyParts[i] && patternParts[i] != "*" && keyParts[i] != "*" {
+			return false
+		}
+	}
+	return true
+}
+
+func lookupKeyHelp(key string) (KeyHelp, bool) {
+	help := loadKeyHelp()
+	if h, ok := help[key]; ok {
+		return h, true
+	}
+	patterns := make([]string, 0, len(help))
+	for pattern := range help {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if keyMatches(pattern, key) {
+			return help[pattern], true
+		}
+	}
+	return KeyHelp{}, false
+}
+
+// questionDefaults walks the questions of all sections with every answer left at
+// its default and calls fn with each question and its default
+func questionDefaults(fn func(q *Question, def string)) {
+	root := newAnswers(nil)
+	for _, section := range configSections("") {
+		sectionAnswers := newAnswers(root)
+		root.items[section.ID] = []*Answers{sectionAnswers}
+		fillDefaults(section.Questions, sectionAnswers, fn)
+	}
+}
+
+// fillDefaults answers questions with their defaults regardless of their
+// dependencies, repeats are walked once without adding an item
+func fillDefaults(questions []Question, a *Answers, fn func(q *Question, def string)) {
+	for i := range questions {
+		q := &questions[i]
+		switch q.Type {
+		case QuestionGroup:
+			fillDefaults(q.Questions, a, fn)
+		case QuestionRepeat:
+			fn(q, "")
+			fillDefaults(q.Questions, newAnswers(a), fn)
+		case QuestionMultiSelect:
+			fn(q, "")
+		default:
+			def := q.defaultValue(a)
+			if q.Type == QuestionBool && def == "" {
+				def = "false"
+			}
+			a.values[q.ID] = def
+			fn(q, def)
+		}
+	}
+}
+
+// formatHelp renders the help of a config key. The question asking for it, if
+// any, adds the wizard's prompt, choices and default.
+func formatHelp(key string, q *Question, def string) string {
+	help, documented := lookupKeyHelp(key)
+	var b strings.Builder
+	fmt.Fprintln(&b, key)
+
+	description := help.Description
+	if description == "" && q != nil {
+		description = q.Help
+	}
+	if description == "" && !documented {
+		description = "No documentation found for this key."
+	}
+	if description != "" {
+		for _, line := range strings.Split(description, "\n") {
+			fmt.Fprintf(&b, "  %s\n", line)
+		}
+	}
+
+	values := help.Values
+	if q != nil && len(q.Options) > 0 {
+		values = q.Options
+	}
+	if len(values) > 0 {
+		fmt.Fprintln(&b, "")
+		fmt.Fprintln(&b, "  Allowed values:")
+		width := 0
+		for _, value := range values {
+			if len(value) > width {
+				width = len(value)
+			}
+		}
+		for _, value := range values {
+			if text, ok := help.ValueDescriptions[value]; ok {
+				fmt.Fprintf(&b, "    %-*s  %s\n", width, value, text)
+			} else {
+				fmt.Fprintf(&b, "    %s\n", value)
+			}
+		}
+	}
+
+	if q != nil {
+		fmt.Fprintln(&b, "")
+		fmt.Fprintf(&b, "  Prompt:  %s\n", q.Prompt)
+		if def != "" {
+			fmt.Fprintf(&b, "  Default: %s\n", def)
+		}
+	}
+
+	example := help.Example
+	if example == "" {
+		example = def
+	}
+	if example != "" {
+		fmt.Fprintln(&b, "")
+		fmt.Fprintln(&b, "  Example:")
+		for i, segment := range strings.Split(key, ".") {
+			if segment == "*" {
+				segment = "<name>"
+			}
+			fmt.Fprintf(&b, "    %s%s:", strings.Repeat("  ", i), segment)
+			if i == strings.Count(key, ".") {
+				fmt.Fprintf(&b, " %s", example)
+			}
+			fmt.Fprintln(&b, "")
+		}
+	}
+
+	if len(help.Sources) > 0 {
+		fmt.Fprintln(&b, "")
+		fmt.Fprintf(&b, "  Source: %s\n", strings.Join(help.Sources, ", "))
+	}
+	return b.String()
+}
+
+// questionHelp is shown when "?" is answered to a prompt
+func questionHelp(q *Question, def string) string {
+	if q.Key == "" {
+		if q.Help == "" {
+			return "No help available for this question.\n"
+		}
+		return q.Help + "\n"
+	}
+	return formatHelp(q.Key, q, def)
+}
+
+// knownKeys lists the keys with documentation or a question asking for them
+func knownKeys() []string {
+	seen := make(map[string]bool)
+	for key := range loadKeyHelp() {
+		seen[key] = true
+	}
+	questionDefaults(func(q *Question, def string) {
+		if q.Key != "" {
+			seen[q.Key] = true
+		}
+	})
+	var keys []string
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// explain prints the help of a config key, or the known keys when key is empty
+func explain(w io.Writer, key string) error {
+	if key == "" {
+		for _, known := range knownKeys() {
+			fmt.Fprintln(w, known)
+		}
+		return nil
+	}
+
+	key = normalizeKey(key)
+	var question *Question
+	var def string
+	questionDefaults(func(q *Question, d string) {
+		if question == nil && q.Key != "" && keyMatches(q.Key, key) {
+			question, def = q, d
+		}
+	})
+	if _, documented := lookupKeyHelp(key); !documented && question == nil {
+		leaf := key[strings.LastIndex(key, ".")+1:]
+		var suggestions []string
+		for _, known := range knownKeys() {
+			if strings.HasSuffix(known, "."+leaf) || known == leaf {
+				suggestions = append(suggestions, known)
+			}
+		}
+		if len(suggestions) > 0 {
+			return fmt.Errorf("no help for %s, did you mean:\n  %s", key, strings.Join(suggestions, "\n  "))
+		}
+		return fmt.Errorf("no help for %s, run 'explain' without a key to list the known keys", key)
+	}
+
+	fmt.Fprint(w, formatHelp(key, question, def))
+	return nil
+}
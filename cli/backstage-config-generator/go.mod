@@ -2,4 +2,9 @@ module backstage-config-generator
 
 go 1.22.3
 
-require gopkg.in/yaml.v3 v3.0.1
+require (
+	golang.org/x/term v0.27.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HelmValues covers the values of the upstream backstage/backstage chart set by the generator
+type HelmValues struct {
+	Backstage      HelmBackstageValues      `yaml:"backstage"`
+	Postgresql     HelmPostgresqlValues     `yaml:"postgresql"`
+	ServiceAccount HelmServiceAccountValues `yaml:"serviceAccount"`
+	ExtraDeploy    []K8sObject              `yaml:"extraDeploy,omitempty"`
+}
+
+type HelmBackstageValues struct {
+	Image          HelmImageValues    `yaml:"image"`
+	ContainerPorts HelmContainerPorts `yaml:"containerPorts"`
+	ExtraEnvVars   []HelmEnvVar       `yaml:"extraEnvVars,omitempty"`
+	AppConfig      *yaml.Node         `yaml:"appConfig"`
+}
+
+type HelmImageValues struct {
+	Registry   string `yaml:"registry"`
+	Repository string `yaml:"repository"`
+	Tag        string `yaml:"tag"`
+}
+
+type HelmContainerPorts struct {
+	Backend int `yaml:"backend"`
+}
+
+type HelmEnvVar struct {
+	Name      string           `yaml:"name"`
+	ValueFrom HelmEnvVarSource `yaml:"valueFrom"`
+}
+
+type HelmEnvVarSource struct {
+	SecretKeyRef HelmSecretKeyRef `yaml:"secretKeyRef"`
+}
+
+type HelmSecretKeyRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type HelmPostgresqlValues struct {
+	Enabled bool                      `yaml:"enabled"`
+	Auth    *HelmPostgresqlAuthValues `yaml:"auth,omitempty"`
+}
+
+type HelmPostgresqlAuthValues struct {
+	Username string `yaml:"username"`
+}
+
+type HelmServiceAccountValues struct {
+	Create bool   `yaml:"create"`
+	Name   string `yaml:"name,omitempty"`
+}
+
+// usesChartPostgresql reports whether the chart should deploy PostgreSQL, which is
+// the case for pg without an external host
+func usesChartPostgresql(config *Config) bool {
+	host := config.Backend.Database.Connection.Host
+	return config.Backend.Database.Client == "pg" && (host == "" || host == "localhost" || host == "127.0.0.1")
+}
+
+// splitImage splits an image reference into the registry, repository and tag values of the chart
+func splitImage(image string) HelmImageValues {
+	values := HelmImageValues{Tag: "latest"}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image, values.Tag = image[:i], image[i+1:]
+	}
+	if registry, repository, ok := strings.Cut(image, "/"); ok && strings.ContainsAny(registry, ".:") {
+		values.Registry, image = registry, repository
+	}
+	values.Repository = image
+	return values
+}
+
+// renderHelmValues renders a values.yaml for the upstream Backstage chart with the
+// app-config under backstage.appConfig and the secrets in a Secret deployed by the chart
+func renderHelmValues(config *Config) ([]byte, error) {
+	deployConfig, err := cloneConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if usesChartPostgresql(deployConfig) {
+		// The chart injects these variables when its PostgreSQL is enabled
+		deployConfig.Backend.Database.Connection = DatabaseConnection{
+			Host:     "${POSTGRES_HOST}",
+			Port:     "${POSTGRES_PORT}",
+			User:     "${POSTGRES_USER}",
+			Password: "${POSTGRES_PASSWORD}",
+		}
+	}
+	env := extractSecrets(deployConfig)
+	if usesChartPostgresql(config) {
+		delete(env, "POSTGRES_PASSWORD")
+	}
+	var tokenVars []string
+	if needsClusterRBAC(config) {
+		tokenVars = serviceAccountTokenVars(deployConfig, env)
+	}
+
+	appConfigData, err := marshalConfig(deployConfig)
+	if err != nil {
+		return nil, err
+	}
+	var appConfig yaml.Node
+	if err := yaml.Unmarshal(appConfigData, &appConfig); err != nil {
+		return nil, err
+	}
+
+	values := HelmValues{
+		Backstage: HelmBackstageValues{
+			Image:          splitImage(defaults.Deploy.Image),
+			ContainerPorts: HelmContainerPorts{Backend: backendPort(config)},
+			AppConfig:      appConfig.Content[0],
+		},
+		Postgresql: HelmPostgresqlValues{Enabled: usesChartPostgresql(config)},
+	}
+	if values.Postgresql.Enabled {
+		values.Postgresql.Auth = &HelmPostgresqlAuthValues{Username: config.Backend.Database.Connection.User}
+	}
+
+	for _, name := range tokenVars {
+		values.Backstage.ExtraEnvVars = append(values.Backstage.ExtraEnvVars, HelmEnvVar{
+			Name:      name,
+			ValueFrom: HelmEnvVarSource{SecretKeyRef: HelmSecretKeyRef{Name: k8sTokenName, Key: "token"}},
+		})
+	}
+	if len(env) > 0 {
+		for _, name := range sortedKeys(env) {
+			values.Backstage.ExtraEnvVars = append(values.Backstage.ExtraEnvVars, HelmEnvVar{
+				Name:      name,
+				ValueFrom: HelmEnvVarSource{SecretKeyRef: HelmSecretKeyRef{Name: k8sSecretName, Key: name}},
+			})
+		}
+		values.ExtraDeploy = append(values.ExtraDeploy, K8sObject{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   K8sMetadata{Name: k8sSecretName},
+			Type:       "Opaque",
+			StringData: env,
+		})
+		warnEmptySecrets(env)
+	}
+
+	if needsClusterRBAC(config) {
+		values.ServiceAccount = HelmServiceAccountValues{Create: true, Name: defaults.Deploy.ServiceAccount}
+		// The chart creates the ServiceAccount itself, only the token and roles are extra
+		values.ExtraDeploy = append(values.ExtraDeploy, ingestorRBAC(config, "{{ .Release.Namespace }}")...)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(values); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,333 @@
+//go:build ignore
+
+// helpgen extracts the help shown by explain and "?" from the annotated YAML
+// examples in site/docs and the plugin READMEs, and writes it to help.yaml.
+// Run it with go generate after changing the docs.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type KeyHelp struct {
+	Description       string            `yaml:"description,omitempty"`
+	Values            []string          `yaml:"values,omitempty"`
+	ValueDescriptions map[string]string `yaml:"valueDescriptions,omitempty"`
+	Example           string            `yaml:"example,omitempty"`
+	Sources           []string          `yaml:"sources"`
+}
+
+const repoRoot = "../.."
+
+// knownRoots are the top-level app-config keys; YAML blocks starting with any
+// other key are fragments of a larger example and are skipped
+var knownRoots = map[string]bool{
+	"app": true, "organization": true, "backend": true, "integrations": true, "proxy": true,
+	"techdocs": true, "auth": true, "scaffolder": true, "catalog": true, "kubernetesIngestor": true,
+	"kubernetes": true, "scaleops": true, "crossplane": true, "kyverno": true, "permission": true,
+	"devpod": true, "vcfAutomation": true,
+}
+
+var (
+	valueListPattern  = regexp.MustCompile(`^[A-Za-z0-9-]+(, ?[A-Za-z0-9-]+)+$`)
+	yamlLinePattern   = regexp.MustCompile(`^(- |[A-Za-z0-9_-]+:( |$))`)
+	headingPattern    = regexp.MustCompile(`^#{2,4} (.+)$`)
+	valueBulletPatern = regexp.MustCompile("^- `([^`]+)`: (.+)$")
+)
+
+func main() {
+	var files []string
+	docs, err := filepath.Glob(filepath.Join(repoRoot, "site", "docs", "plugins", "*", "*", "*.md"))
+	if err != nil {
+		fail(err)
+	}
+	readmes, err := filepath.Glob(filepath.Join(repoRoot, "plugins", "*", "README.md"))
+	if err != nil {
+		fail(err)
+	}
+	files = append(files, docs...)
+	files = append(files, readmes...)
+	sort.Strings(files)
+
+	help := make(map[string]*KeyHelp)
+	for _, file := range files {
+		if err := extractFile(file, help); err != nil {
+			fail(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "# Code generated by helpgen.go from site/docs and the plugin READMEs. DO NOT EDIT.")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(help); err != nil {
+		fail(err)
+	}
+	if err := encoder.Close(); err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile("help.yaml", buf.Bytes(), 0644); err != nil {
+		fail(err)
+	}
+	fmt.Printf("Wrote help for %d keys from %d files\n", len(help), len(files))
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "helpgen: %v\n", err)
+	os.Exit(1)
+}
+
+func extractFile(path string, help map[string]*KeyHelp) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	source, err := filepath.Rel(repoRoot, path)
+	if err != nil {
+		return err
+	}
+	source = filepath.ToSlash(source)
+
+	var blocks []string
+	var block strings.Builder
+	inBlock := false
+	valueDocs := make(map[string][][2]string)
+	heading := ""
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case inBlock && strings.HasPrefix(trimmed, "```"):
+			blocks = append(blocks, block.String())
+			block.Reset()
+			inBlock = false
+		case inBlock:
+			block.WriteString(line + "\n")
+		case trimmed == "```yaml" || trimmed == "```yml":
+			inBlock = true
+		case headingPattern.MatchString(trimmed):
+			heading = lowerCamel(headingPattern.FindStringSubmatch(trimmed)[1])
+		case heading != "" && valueBulletPatern.MatchString(trimmed):
+			m := valueBulletPatern.FindStringSubmatch(trimmed)
+			valueDocs[heading] = append(valueDocs[heading], [2]string{m[1], strings.TrimSpace(m[2])})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	found := make(map[string]bool)
+	for _, text := range blocks {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(text), &doc); err != nil || len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode || !allKnownRoots(root) {
+			continue
+		}
+		walk(root, "", strings.Split(text, "\n"), source, help, found)
+	}
+
+	// Headings such as "Name Model" followed by "- `value`: description" bullets
+	// document the values of the key with the same name
+	for key := range found {
+		leaf := key[strings.LastIndex(key, ".")+1:]
+		docs, ok := valueDocs[leaf]
+		if !ok || help[key].ValueDescriptions != nil {
+			continue
+		}
+		help[key].ValueDescriptions = make(map[string]string)
+		var values []string
+		for _, doc := range docs {
+			help[key].ValueDescriptions[doc[0]] = doc[1]
+			values = append(values, doc[0])
+		}
+		if len(help[key].Values) == 0 {
+			help[key].Values = values
+		}
+	}
+	return nil
+}
+
+func allKnownRoots(root *yaml.Node) bool {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if !knownRoots[root.Content[i].Value] {
+			return false
+		}
+	}
+	return true
+}
+
+// walk records the help of every key under node. lines are the lines of the
+// YAML block, used to tell comments annotating a key from the ones introducing
+// a group of keys or the whole block.
+func walk(node *yaml.Node, path string, lines []string, source string, help map[string]*KeyHelp, found map[string]bool) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		// List items share the key of the list
+		for _, item := range node.Content {
+			walk(item, path, lines, source, help, found)
+		}
+		return
+	case yaml.MappingNode:
+	default:
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		segment := keyNode.Value
+		if strings.ContainsAny(segment, "/<> ") {
+			segment = "*"
+		}
+		key := segment
+		if path != "" {
+			key = path + "." + segment
+		}
+
+		var description, lineComment string
+		// Comments on root keys name the file or introduce the example, such as
+		// "# app-config.yaml", they do not describe the key
+		if path != "" {
+			if annotates(keyNode, lines) {
+				description = headCommentText(keyNode.HeadComment)
+			}
+			lineComment = commentText(keyNode.LineComment)
+			if lineComment == "" {
+				lineComment = commentText(valueNode.LineComment)
+			}
+		}
+		var values []string
+		if valueListPattern.MatchString(lineComment) {
+			values = splitValues(lineComment)
+		} else if lineComment != "" {
+			description = strings.TrimSpace(description + "\n" + lineComment)
+		}
+
+		entry, ok := help[key]
+		if !ok {
+			entry = &KeyHelp{}
+		}
+		if entry.Description == "" {
+			entry.Description = description
+		}
+		if len(entry.Values) == 0 {
+			entry.Values = values
+		}
+		if entry.Example == "" {
+			entry.Example = example(valueNode)
+		}
+		if entry.Description != "" || len(entry.Values) > 0 || entry.Example != "" {
+			if !contains(entry.Sources, source) {
+				entry.Sources = append(entry.Sources, source)
+			}
+			help[key] = entry
+			found[key] = true
+		}
+
+		walk(valueNode, key, lines, source, help, found)
+	}
+}
+
+// annotates reports whether the head comment of a key sits on the lines right
+// above it. The YAML parser also attaches comments separated by a blank line,
+// which introduce the keys that follow rather than this one.
+func annotates(keyNode *yaml.Node, lines []string) bool {
+	if keyNode.HeadComment == "" || keyNode.Line < 2 || keyNode.Line-2 >= len(lines) {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimSpace(lines[keyNode.Line-2]), "#")
+}
+
+// commentText keeps the prose of a comment and drops commented-out YAML
+func commentText(comment string) string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if line == "" || yamlLinePattern.MatchString(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// headCommentText keeps the prose right above a key. Prose followed by
+// commented-out YAML documents that YAML, such as an optional key, and not the
+// key the comment is attached to.
+func headCommentText(comment string) string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if yamlLinePattern.MatchString(line) || strings.HasPrefix(line, "- ") {
+			lines = nil
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func splitValues(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		values = append(values, strings.TrimSpace(value))
+	}
+	return values
+}
+
+// example renders scalar values and lists of scalars, nested structures are
+// documented by their own keys
+func example(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value
+	case yaml.SequenceNode:
+		var items []string
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return ""
+			}
+			items = append(items, item.Value)
+		}
+		if len(items) == 0 {
+			return ""
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	}
+	return ""
+}
+
+func lowerCamel(heading string) string {
+	words := strings.Fields(heading)
+	for i, word := range words {
+		if i == 0 {
+			words[i] = strings.ToLower(word)
+		} else {
+			words[i] = strings.ToUpper(word[:1]) + strings.ToLower(word[1:])
+		}
+	}
+	return strings.Join(words, "")
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ingestorAnnotationPrefix is the default kubernetesIngestor.annotationPrefix
+const ingestorAnnotationPrefix = "terasky.backstage.io"
+
+// maxEntityNameLength is the longest entity name the ingestor accepts, longer
+// names are skipped with a warning
+const maxEntityNameLength = 63
+
+// MappingPreview is the Backstage entity the ingestor creates for a workload
+type MappingPreview struct {
+	Ref     string
+	Title   string
+	System  string
+	Warning string
+}
+
+// mappingModel returns a mapping model lowercased the way the ingestor reads
+// it, or the ingestor's default when it is not set
+func mappingModel(model, def string) string {
+	if model == "" {
+		return def
+	}
+	return strings.ToLower(model)
+}
+
+// previewMapping applies the mapping models to a workload the way the ingestor's
+// entity provider does, including the annotations overriding them
+func previewMapping(mappings MappingsConfig, object K8sObject, cluster string) MappingPreview {
+	name, namespace := object.Metadata.Name, object.Metadata.Namespace
+	annotations := object.Metadata.Annotations
+
+	entityNamespace := "default"
+	switch mappingModel(mappings.NamespaceModel, "default") {
+	case "cluster":
+		entityNamespace = cluster
+	case "namespace":
+		entityNamespace = namespaceOrDefault(namespace)
+	}
+	if override := annotations[ingestorAnnotationPrefix+"/backstage-namespace"]; override != "" {
+		entityNamespace = override
+	}
+
+	referencesNamespace := "default"
+	if mappingModel(mappings.ReferencesNamespaceModel, "default") == "same" {
+		referencesNamespace = namespace
+	}
+
+	entityName := name
+	switch mappingModel(mappings.NameModel, "name") {
+	case "name-cluster":
+		entityName = name + "-" + cluster
+	case "name-namespace":
+		if namespace != "" {
+			entityName = name + "-" + namespace
+		}
+	case "name-kind":
+		kind := strings.ToLower(object.Kind)
+		if kind == "" {
+			kind = "res"
+		}
+		if len(kind) > 5 {
+			kind = kind[:5]
+		}
+		entityName = name + "-" + kind
+	}
+
+	title := name
+	switch mappingModel(mappings.TitleModel, "name") {
+	case "name-cluster":
+		title = name + "-" + cluster
+	case "name-namespace":
+		if namespace != "" {
+			title = name + "-" + namespace
+		}
+	}
+
+	system := "default"
+	switch mappingModel(mappings.SystemModel, "namespace") {
+	case "cluster":
+		system = cluster
+	case "namespace":
+		system = namespaceOrDefault(namespace)
+	case "cluster-namespace":
+		system = cluster
+		if namespace != "" {
+			system = cluster + "-" + namespace
+		}
+	}
+	systemRef := referencesNamespace + "/" + system
+	if override := annotations[ingestorAnnotationPrefix+"/system"]; override != "" {
+		systemRef = override
+	}
+
+	preview := MappingPreview{
+		Ref:    fmt.Sprintf("component:%s/%s", entityNamespace, entityName),
+		Title:  title,
+		System: "system:" + systemRef,
+	}
+	if len(entityName) > maxEntityNameLength {
+		preview.Warning = fmt.Sprintf("the name is %d characters long, the ingestor skips entities with names over %d characters", len(entityName), maxEntityNameLength)
+	}
+	return preview
+}
+
+func namespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return "default"
+	}
+	return namespace
+}
+
+func printMappingPreview(w io.Writer, preview MappingPreview) {
+	fmt.Fprintf(w, "  Entity: %s\n", preview.Ref)
+	fmt.Fprintf(w, "  Title:  %s\n", preview.Title)
+	fmt.Fprintf(w, "  System: %s\n", preview.System)
+	if preview.Warning != "" {
+		fmt.Fprintf(w, "  Warning: %s\n", preview.Warning)
+	}
+}
+
+// readManifests reads the Kubernetes objects of a multi-document YAML file,
+// skipping empty documents and objects without a kind
+func readManifests(path string) ([]K8sObject, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var objects []K8sObject
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var object K8sObject
+		err := decoder.Decode(&object)
+		if errors.Is(err, io.EOF) {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if object.Kind != "" {
+			objects = append(objects, object)
+		}
+	}
+}
+
+// validateManifest accepts a file with at least one named Kubernetes object
+func validateManifest(value string, a *Answers) error {
+	objects, err := readManifests(value)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if object.Metadata.Name != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("no Kubernetes object with a name found in %s", value)
+}
+
+// defaultWorkloadTypes are ingested unless disableDefaultWorkloadTypes is set
+var defaultWorkloadTypes = []CustomWorkloadType{
+	{Group: "apps", ApiVersion: "v1", Plural: "deployments"},
+	{Group: "apps", ApiVersion: "v1", Plural: "statefulsets"},
+	{Group: "apps", ApiVersion: "v1", Plural: "daemonsets"},
+	{Group: "batch", ApiVersion: "v1", Plural: "cronjobs"},
+}
+
+// IngestPreview is what the ingestor makes of one object of the manifests
+type IngestPreview struct {
+	Source string
+	Object K8sObject
+	// Category is "workload", "claim", "composite" or "xrd", empty for objects the ingestor ignores
+	Category string
+	// Skipped explains why an object of an ingested category is left out
+	Skipped  string
+	Mapping  MappingPreview
+	Entities []string
+}
+
+// defaultIngestorConfig is the ingestor config the wizard generates when every default is accepted
+func defaultIngestorConfig() *KubernetesIngestorConfig {
+	a := newAnswers(nil)
+	fillDefaults(kubernetesIngestorSection.Questions, a, func(q *Question, def string) {})
+	a.values["enabled"] = "true"
+	var config Config
+	kubernetesIngestorSection.Build(a, &config)
+	return config.KubernetesIngestor
+}
+
+// readManifestDir reads the objects of a manifest file, or of every YAML file under a directory
+func readManifestDir(path string) (map[string][]K8sObject, []string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var files []string
+	if info.IsDir() {
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if ext := filepath.Ext(file); !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		files = []string{path}
+	}
+
+	objects := make(map[string][]K8sObject)
+	for _, file := range files {
+		fileObjects, err := readManifests(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		objects[file] = fileObjects
+	}
+	return objects, files, nil
+}
+
+// specValue returns the value at a path of field names under spec
+func specValue(object K8sObject, fields ...string) interface{} {
+	var value interface{} = object.Spec
+	for _, field := range fields {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[field]
+	}
+	return value
+}
+
+func specString(object K8sObject, fields ...string) string {
+	s, _ := specValue(object, fields...).(string)
+	return s
+}
+
+func apiGroup(apiVersion string) string {
+	if i := strings.Index(apiVersion, "/"); i >= 0 {
+		return apiVersion[:i]
+	}
+	return ""
+}
+
+func apiVersionName(apiVersion string) string {
+	return apiVersion[strings.Index(apiVersion, "/")+1:]
+}
+
+// pluralKind approximates the plural resource name of a kind, CRDs declare the exact one
+func pluralKind(kind string) string {
+	plural := strings.ToLower(kind)
+	switch {
+	case strings.HasSuffix(plural, "s"), strings.HasSuffix(plural, "x"), strings.HasSuffix(plural, "ch"), strings.HasSuffix(plural, "sh"):
+		return plural + "es"
+	case strings.HasSuffix(plural, "y") && !strings.HasSuffix(plural, "ey"):
+		return plural[:len(plural)-1] + "ies"
+	}
+	return plural + "s"
+}
+
+func isXRD(object K8sObject) bool {
+	return apiGroup(object.APIVersion) == "apiextensions.crossplane.io" && object.Kind == "CompositeResourceDefinition"
+}
+
+// xrdVersions lists the version names an XRD serves
+func xrdVersions(xrd K8sObject) []string {
+	var names []string
+	versions, _ := specValue(xrd, "versions").([]interface{})
+	for _, version := range versions {
+		if m, ok := version.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// xrdKinds indexes the claim and v2 composite kinds the XRDs define by "group/Kind"
+func xrdKinds(objects []K8sObject) (claims, composites map[string]bool) {
+	claims, composites = make(map[string]bool), make(map[string]bool)
+	for _, object := range objects {
+		if !isXRD(object) {
+			continue
+		}
+		group := specString(object, "group")
+		if kind := specString(object, "claimNames", "kind"); kind != "" {
+			claims[group+"/"+kind] = true
+		}
+		if scope := specString(object, "scope"); scope == "Namespaced" || scope == "Cluster" {
+			composites[group+"/"+specString(object, "names", "kind")] = true
+		}
+	}
+	return claims, composites
+}
+
+// previewIngestion applies the ingestor rules to local manifests, the way the
+// ingestor's data providers filter what they fetch from a cluster
+func previewIngestion(ingestor *KubernetesIngestorConfig, objects map[string][]K8sObject, files []string, cluster string) []IngestPreview {
+	var all []K8sObject
+	for _, file := range files {
+		all = append(all, objects[file]...)
+	}
+	claimKinds, compositeKinds := xrdKinds(all)
+
+	workloadTypes := ingestor.Components.CustomWorkloadTypes
+	if !ingestor.Components.DisableDefaultWorkloadTypes {
+		workloadTypes = append(append([]CustomWorkloadType{}, defaultWorkloadTypes...), workloadTypes...)
+	}
+	isWorkload := func(object K8sObject) bool {
+		for _, t := range workloadTypes {
+			if t.Group == apiGroup(object.APIVersion) && t.ApiVersion == apiVersionName(object.APIVersion) && t.Plural == pluralKind(object.Kind) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var previews []IngestPreview
+	for _, file := range files {
+		for _, object := range objects[file] {
+			preview := IngestPreview{Source: file, Object: object}
+			kindKey := apiGroup(object.APIVersion) + "/" + object.Kind
+			switch {
+			case isXRD(object):
+				preview.Category = "xrd"
+				previewXRD(ingestor, &preview)
+				previews = append(previews, preview)
+				continue
+			case claimKinds[kindKey] || specValue(object, "resourceRef") != nil:
+				preview.Category = "claim"
+				if !ingestor.Crossplane.Claims.IngestAllClaims {
+					preview.Skipped = "ingestAllClaims is disabled"
+				}
+			case compositeKinds[kindKey] || specValue(object, "crossplane") != nil:
+				preview.Category = "composite"
+			case isWorkload(object):
+				preview.Category = "workload"
+			default:
+				previews = append(previews, preview)
+				continue
+			}
+
+			if !ingestor.Components.Enabled {
+				// Claims and composites are ingested by the same provider as workloads
+				preview.Skipped = "components are disabled"
+			}
+			if preview.Skipped == "" {
+				preview.Skipped = ingestFilter(ingestor, object)
+			}
+			if preview.Skipped == "" {
+				mapped := object
+				if preview.Category != "workload" {
+					// Claims and composites ignore the backstage-namespace annotation
+					mapped.Metadata.Annotations = withoutKey(object.Metadata.Annotations, ingestorAnnotationPrefix+"/backstage-namespace")
+				}
+				preview.Mapping = previewMapping(ingestor.Mappings, mapped, cluster)
+				if preview.Mapping.Warning != "" {
+					preview.Skipped = preview.Mapping.Warning
+				}
+			}
+			previews = append(previews, preview)
+		}
+	}
+	return previews
+}
+
+// ingestFilter returns why the component filters leave an object out, or ""
+func ingestFilter(ingestor *KubernetesIngestorConfig, object K8sObject) string {
+	annotations := object.Metadata.Annotations
+	if annotations[ingestorAnnotationPrefix+"/exclude-from-catalog"] != "" {
+		return "annotated with " + ingestorAnnotationPrefix + "/exclude-from-catalog"
+	}
+	if ingestor.Components.OnlyIngestAnnotatedResources {
+		if annotations[ingestorAnnotationPrefix+"/add-to-catalog"] == "" {
+			return "onlyIngestAnnotatedResources is enabled and it is not annotated with " + ingestorAnnotationPrefix + "/add-to-catalog"
+		}
+		return ""
+	}
+	if contains(ingestor.Components.ExcludedNamespaces, object.Metadata.Namespace) {
+		return "namespace " + object.Metadata.Namespace + " is excluded"
+	}
+	return ""
+}
+
+// previewXRD lists the template and API entities generated from each version of an XRD
+func previewXRD(ingestor *KubernetesIngestorConfig, preview *IngestPreview) {
+	xrd := preview.Object
+	annotations := xrd.Metadata.Annotations
+	scope := specString(xrd, "scope")
+	legacy := scope == "" || scope == "LegacyCluster"
+	switch {
+	case !ingestor.Crossplane.Xrds.Enabled:
+		preview.Skipped = "xrds are disabled"
+	case annotations[ingestorAnnotationPrefix+"/exclude-from-catalog"] != "":
+		preview.Skipped = "annotated with " + ingestorAnnotationPrefix + "/exclude-from-catalog"
+	case !ingestor.Crossplane.Xrds.IngestAllXRDs && annotations[ingestorAnnotationPrefix+"/add-to-catalog"] == "":
+		preview.Skipped = "ingestAllXRDs is disabled and it is not annotated with " + ingestorAnnotationPrefix + "/add-to-catalog"
+	case legacy && specString(xrd, "claimNames", "kind") == "":
+		preview.Skipped = "it defines no claim kind"
+	}
+	if preview.Skipped != "" {
+		return
+	}
+
+	kind := specString(xrd, "names", "kind")
+	if legacy {
+		kind = specString(xrd, "claimNames", "kind")
+	}
+	for _, version := range xrdVersions(xrd) {
+		preview.Entities = append(preview.Entities,
+			fmt.Sprintf("template:default/%s-%s", xrd.Metadata.Name, version),
+			fmt.Sprintf("api:default/%s-%s--%s", strings.ToLower(kind), specString(xrd, "group"), version))
+	}
+}
+
+func withoutKey(m map[string]string, key string) map[string]string {
+	copied := make(map[string]string)
+	for k, v := range m {
+		if k != key {
+			copied[k] = v
+		}
+	}
+	return copied
+}
+
+var ingestCategoryTitles = []struct{ category, title string }{
+	{"workload", "Workloads"},
+	{"claim", "Crossplane Claims"},
+	{"composite", "Crossplane Composite Resources"},
+	{"xrd", "Crossplane XRDs"},
+}
+
+func printIngestPreview(w io.Writer, previews []IngestPreview) {
+	describe := func(p IngestPreview) string {
+		name := p.Object.Metadata.Name
+		if p.Object.Metadata.Namespace != "" {
+			name = p.Object.Metadata.Namespace + "/" + name
+		}
+		return fmt.Sprintf("%s %s (%s)", p.Object.Kind, name, p.Source)
+	}
+
+	var skipped []IngestPreview
+	for _, category := range ingestCategoryTitles {
+		heading := false
+		for _, p := range previews {
+			if p.Category != category.category {
+				continue
+			}
+			if p.Skipped != "" {
+				skipped = append(skipped, p)
+				continue
+			}
+			if !heading {
+				fmt.Fprintf(w, "\n%s\n%s\n", category.title, strings.Repeat("=", len(category.title)))
+				heading = true
+			}
+			fmt.Fprintln(w, describe(p))
+			if p.Category == "xrd" {
+				for _, entity := range p.Entities {
+					fmt.Fprintf(w, "  %s\n", entity)
+				}
+			} else {
+				printMappingPreview(w, p.Mapping)
+			}
+		}
+	}
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "\nNot Ingested\n============\n")
+		for _, p := range skipped {
+			fmt.Fprintf(w, "%s: %s\n", describe(p), p.Skipped)
+		}
+	}
+	ignored := 0
+	for _, p := range previews {
+		if p.Category == "" {
+			ignored++
+		}
+	}
+	switch {
+	case ignored == 1:
+		fmt.Fprintf(w, "\n1 other object is not a workload, claim or XRD\n")
+	case ignored > 1:
+		fmt.Fprintf(w, "\n%d other objects are not workloads, claims or XRDs\n", ignored)
+	}
+}
+
+// formatWorkloadType is the group/version/plural form workload types are selected by
+func formatWorkloadType(t CustomWorkloadType) string {
+	return t.Group + "/" + t.ApiVersion + "/" + t.Plural
+}
+
+func parseWorkloadType(value string) CustomWorkloadType {
+	parts := strings.SplitN(value, "/", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return CustomWorkloadType{Group: parts[0], ApiVersion: parts[1], Plural: parts[2]}
+}
+
+// isDefaultWorkloadType reports whether t names a resource the ingestor already
+// ingests by default, in any version
+func isDefaultWorkloadType(t CustomWorkloadType) bool {
+	for _, d := range defaultWorkloadTypes {
+		if d.Group == t.Group && d.Plural == t.Plural {
+			return true
+		}
+	}
+	return false
+}
+
+// crdWorkloadTypes lists a workload type for every served version of the CRDs in
+// a file or directory. Duplicates are dropped and the types overlapping the
+// default workload types are returned apart.
+func crdWorkloadTypes(path string) (types, defaults []CustomWorkloadType, err error) {
+	objects, files, err := readManifestDir(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]bool)
+	for _, file := range files {
+		for _, object := range objects[file] {
+			if apiGroup(object.APIVersion) != "apiextensions.k8s.io" || object.Kind != "CustomResourceDefinition" {
+				continue
+			}
+			versions, _ := specValue(object, "versions").([]interface{})
+			for _, version := range versions {
+				v, _ := version.(map[string]interface{})
+				name, _ := v["name"].(string)
+				if served, ok := v["served"].(bool); name == "" || ok && !served {
+					continue
+				}
+				t := CustomWorkloadType{Group: specString(object, "group"), ApiVersion: name, Plural: specString(object, "names", "plural")}
+				if seen[formatWorkloadType(t)] {
+					continue
+				}
+				seen[formatWorkloadType(t)] = true
+				if isDefaultWorkloadType(t) {
+					defaults = append(defaults, t)
+				} else {
+					types = append(types, t)
+				}
+			}
+		}
+	}
+	if len(types)+len(defaults) == 0 {
+		return nil, nil, fmt.Errorf("no CustomResourceDefinitions found in %s", path)
+	}
+	return types, defaults, nil
+}
+
+// discoveredWorkloadTypes are the options of the CRD multi-select
+func discoveredWorkloadTypes(path string) []string {
+	types, defaults, err := crdWorkloadTypes(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading CRDs: %v\n", err)
+		return nil
+	}
+	for _, t := range defaults {
+		fmt.Printf("Skipping %s, it is one of the default workload types\n", formatWorkloadType(t))
+	}
+	var options []string
+	for _, t := range types {
+		options = append(options, formatWorkloadType(t))
+	}
+	return options
+}
+
+var (
+	apiGroupPattern   = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)+$`)
+	apiVersionPattern = regexp.MustCompile(`^v[1-9][0-9]*((alpha|beta)[1-9][0-9]*)?$`)
+	pluralPattern     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+func validateAPIGroup(value string, a *Answers) error {
+	if !apiGroupPattern.MatchString(value) {
+		return fmt.Errorf("%s is not an API group such as argoproj.io", value)
+	}
+	return nil
+}
+
+func validateAPIVersion(value string, a *Answers) error {
+	if !apiVersionPattern.MatchString(value) {
+		return fmt.Errorf("%s is not an API version such as v1 or v1alpha1", value)
+	}
+	return nil
+}
+
+// validateWorkloadPlural completes a workload type and rejects types that are
+// ingested already, by default, from the CRDs or as an earlier custom type
+func validateWorkloadPlural(value string, a *Answers) error {
+	if !pluralPattern.MatchString(value) {
+		return fmt.Errorf("%s is not a lowercase plural resource name such as rollouts", value)
+	}
+	t := CustomWorkloadType{Group: a.Get("group"), ApiVersion: a.Get("apiVersion"), Plural: value}
+	if isDefaultWorkloadType(t) {
+		return fmt.Errorf("%s/%s is one of the default workload types", t.Group, t.Plural)
+	}
+	existing := a.List("discoveredWorkloadTypes")
+	for _, item := range a.Items("customWorkloadTypes") {
+		existing = append(existing, formatWorkloadType(CustomWorkloadType{Group: item.Get("group"), ApiVersion: item.Get("apiVersion"), Plural: item.Get("plural")}))
+	}
+	if contains(existing, formatWorkloadType(t)) {
+		return fmt.Errorf("%s is already added", formatWorkloadType(t))
+	}
+	return nil
+}
+
+// validateCRDs accepts a file or directory with at least one CRD
+func validateCRDs(value string, a *Answers) error {
+	_, _, err := crdWorkloadTypes(value)
+	return err
+}
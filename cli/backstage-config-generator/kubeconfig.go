@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kubeconfig structs, limited to the fields needed to build ClusterConfig entries
+type Kubeconfig struct {
+	CurrentContext string              `yaml:"current-context"`
+	Clusters       []KubeconfigCluster `yaml:"clusters"`
+	Contexts       []KubeconfigContext `yaml:"contexts"`
+	Users          []KubeconfigUser    `yaml:"users"`
+
+	// dir resolves relative certificate-authority paths, like kubectl does
+	dir string
+}
+
+type KubeconfigCluster struct {
+	Name    string `yaml:"name"`
+	Cluster struct {
+		Server                   string `yaml:"server"`
+		CertificateAuthority     string `yaml:"certificate-authority"`
+		CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+	} `yaml:"cluster"`
+}
+
+type KubeconfigContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster string `yaml:"cluster"`
+		User    string `yaml:"user"`
+	} `yaml:"context"`
+}
+
+type KubeconfigUser struct {
+	Name string `yaml:"name"`
+	User struct {
+		Token        string                  `yaml:"token"`
+		TokenFile    string                  `yaml:"tokenFile"`
+		Exec         *KubeconfigExecConfig   `yaml:"exec"`
+		AuthProvider *KubeconfigAuthProvider `yaml:"auth-provider"`
+	} `yaml:"user"`
+}
+
+type KubeconfigExecConfig struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+}
+
+type KubeconfigAuthProvider struct {
+	Name string `yaml:"name"`
+}
+
+func defaultKubeconfigPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return strings.Split(env, string(os.PathListSeparator))[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+func loadKubeconfig(path string) (*Kubeconfig, error) {
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var kubeconfig Kubeconfig
+	if err := yaml.Unmarshal(data, &kubeconfig); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(kubeconfig.Contexts) == 0 {
+		return nil, fmt.Errorf("no contexts found in %s", path)
+	}
+	kubeconfig.dir = filepath.Dir(path)
+	return &kubeconfig, nil
+}
+
+// clusterFromContext converts a kubeconfig context into a Backstage cluster entry.
+// Credentials are never copied; they are written as ${VAR} placeholders instead.
+// A local certificate-authority file is inlined as caData, its path would not
+// exist on the Backstage server.
+func (k *Kubeconfig) clusterFromContext(ctx KubeconfigContext) (ClusterConfig, error) {
+	var kubeCluster *KubeconfigCluster
+	for i := range k.Clusters {
+		if k.Clusters[i].Name == ctx.Context.Cluster {
+			kubeCluster = &k.Clusters[i]
+			break
+		}
+	}
+	if kubeCluster == nil {
+		return ClusterConfig{}, fmt.Errorf("context %s references unknown cluster %s", ctx.Name, ctx.Context.Cluster)
+	}
+
+	cluster := ClusterConfig{
+		Name:          ctx.Name,
+		Url:           kubeCluster.Cluster.Server,
+		SkipTLSVerify: kubeCluster.Cluster.InsecureSkipTLSVerify,
+		CAData:        kubeCluster.Cluster.CertificateAuthorityData,
+	}
+	if caFile := kubeCluster.Cluster.CertificateAuthority; caFile != "" && cluster.CAData == "" {
+		if !filepath.IsAbs(caFile) {
+			caFile = filepath.Join(k.dir, caFile)
+		}
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot inline the CA of %s, set caData or a caFile path that exists on the Backstage server: %v\n", ctx.Name, err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Note: inlined %s as the caData of %s, the file would not exist on the Backstage server\n", caFile, ctx.Name)
+			cluster.CAData = base64.StdEncoding.EncodeToString(ca)
+		}
+	}
+
+	dropSkipTLSVerifyWithoutCA(&cluster)
+
+	var kubeUser *KubeconfigUser
+	for i := range k.Users {
+		if k.Users[i].Name == ctx.Context.User {
+			kubeUser = &k.Users[i]
+			break
+		}
+	}
+
+	cluster.AuthProvider = "serviceAccount"
+	if kubeUser != nil {
+		unknown := ""
+		switch {
+		case kubeUser.User.Exec != nil:
+			cluster.AuthProvider = authProviderForExec(kubeUser.User.Exec)
+			unknown = "exec command " + kubeUser.User.Exec.Command
+		case kubeUser.User.AuthProvider != nil:
+			cluster.AuthProvider = authProviderForPlugin(kubeUser.User.AuthProvider.Name)
+			unknown = "auth provider " + kubeUser.User.AuthProvider.Name
+		}
+		if cluster.AuthProvider == "" {
+			fmt.Fprintf(os.Stderr, "Warning: %s uses the unknown %s, falling back to a service account token\n", ctx.Name, unknown)
+			cluster.AuthProvider = "serviceAccount"
+		}
+	}
+
+	switch cluster.AuthProvider {
+	case "serviceAccount":
+		cluster.ServiceAccountToken = fmt.Sprintf("${%s}", envVarName("K8S", ctx.Name, "TOKEN"))
+	case "oidc":
+		cluster.OidcTokenProvider = "microsoft"
+	}
+
+	return cluster, nil
+}
+
+// authProviderForExec maps well-known exec credential plugins to Backstage auth
+// providers, it returns "" for other commands
+func authProviderForExec(exec *KubeconfigExecConfig) string {
+	command := filepath.Base(exec.Command)
+	switch {
+	case command == "aws" || command == "aws-iam-authenticator":
+		return "aws"
+	case command == "gke-gcloud-auth-plugin" || command == "gcloud":
+		return "google"
+	case command == "kubelogin":
+		return "azure"
+	case strings.Contains(command, "oidc"):
+		return "oidc"
+	}
+	for _, arg := range exec.Args {
+		if arg == "oidc-login" {
+			return "oidc"
+		}
+	}
+	return ""
+}
+
+func authProviderForPlugin(name string) string {
+	switch name {
+	case "gcp":
+		return "google"
+	case "azure":
+		return "azure"
+	case "oidc":
+		return "oidc"
+	}
+	return ""
+}
+
+var envVarInvalidChars = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// envVarName builds an environment variable name such as K8S_MY_CLUSTER_TOKEN
+func envVarName(parts ...string) string {
+	name := strings.ToUpper(strings.Join(parts, "_"))
+	return strings.Trim(envVarInvalidChars.ReplaceAllString(name, "_"), "_")
+}
+
+// kubeconfigContexts lists the contexts of a kubeconfig file for selection
+func kubeconfigContexts(path string) []string {
+	kubeconfig, err := loadKubeconfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading kubeconfig: %v\n", err)
+		return nil
+	}
+	var names []string
+	for _, ctx := range kubeconfig.Contexts {
+		names = append(names, ctx.Name)
+	}
+	return names
+}
+
+func importKubeconfigClusters(path string, contexts []string) []ClusterConfig {
+	kubeconfig, err := loadKubeconfig(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading kubeconfig: %v\n", err)
+		return nil
+	}
+
+	var clusters []ClusterConfig
+	for _, ctx := range kubeconfig.Contexts {
+		if !contains(contexts, ctx.Name) {
+			continue
+		}
+		cluster, err := kubeconfig.clusterFromContext(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Skipping context: %v\n", err)
+			continue
+		}
+		fmt.Printf("Imported cluster %s (%s, authProvider=%s)\n", cluster.Name, cluster.Url, cluster.AuthProvider)
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
@@ -1,9 +1,10 @@
 package main
 
 import (
-	"flag"
+	"bufio"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -18,7 +19,7 @@ type Config struct {
 	Proxy             *ProxyConfig            `yaml:"proxy"`
 	Techdocs          TechdocsConfig         `yaml:"techdocs"`
 	Auth              AuthConfig             `yaml:"auth"`
-	Scaffolder        ScaffolderConfig       `yaml:"scaffolder"`
+	Scaffolder        ScaffolderConfig       `yaml:"scaffolder,omitempty"`
 	Catalog           CatalogConfig          `yaml:"catalog"`
 	KubernetesIngestor *KubernetesIngestorConfig `yaml:"kubernetesIngestor,omitempty"`
 	Kubernetes        *KubernetesConfig          `yaml:"kubernetes,omitempty"`
@@ -27,6 +28,7 @@ type Config struct {
 	Kyverno           *KyvernoConfig             `yaml:"kyverno,omitempty"`
 	Permission        PermissionConfig       `yaml:"permission"`
 	Devpod            *DevpodConfig              `yaml:"devpod,omitempty"`
+	VcfAutomation     *VcfAutomationConfig       `yaml:"vcfAutomation,omitempty"`
 }
 
 type AppConfig struct {
@@ -45,6 +47,7 @@ type BackendConfig struct {
 	CORS      CORSConfig     `yaml:"cors"`
 	Database  DatabaseConfig `yaml:"database"`
 	Reading   ReadingConfig  `yaml:"reading"`
+	WorkingDirectory string  `yaml:"workingDirectory,omitempty"`
 }
 
 type ListenConfig struct {
@@ -62,8 +65,44 @@ type CORSConfig struct {
 }
 
 type DatabaseConfig struct {
-	Client     string `yaml:"client"`
-	Connection string `yaml:"connection"`
+	Client     string             `yaml:"client"`
+	Connection DatabaseConnection `yaml:"connection"`
+}
+
+// DatabaseConnection is a plain string for better-sqlite3 (":memory:" or a
+// directory) and a mapping of connection settings for pg
+type DatabaseConnection struct {
+	Path     string `yaml:"-"`
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	User     string `yaml:"user"`
+	Password string `yaml:"password" secret:"true"`
+}
+
+type databaseConnectionFields DatabaseConnection
+
+type databaseConfigFields struct {
+	Client     string      `yaml:"client"`
+	Connection interface{} `yaml:"connection"`
+}
+
+// MarshalYAML writes the connection as a plain string for better-sqlite3 only, a
+// pg connection stays a mapping even without a host. A database the wizard did
+// not ask about keeps the empty string.
+func (d DatabaseConfig) MarshalYAML() (interface{}, error) {
+	fields := databaseConfigFields{Client: d.Client, Connection: databaseConnectionFields(d.Connection)}
+	if d.Client == "better-sqlite3" || (d.Client == "" && d.Connection == DatabaseConnection{}) {
+		fields.Connection = d.Connection.Path
+	}
+	return fields, nil
+}
+
+func (c *DatabaseConnection) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = DatabaseConnection{Path: node.Value}
+		return nil
+	}
+	return node.Decode((*databaseConnectionFields)(c))
 }
 
 type ReadingConfig struct {
@@ -80,7 +119,7 @@ type IntegrationsConfig struct {
 
 type GithubIntegrationConfig struct {
 	Host  string `yaml:"host"`
-	Token string `yaml:"token"`
+	Token string `yaml:"token" secret:"true"`
 }
 
 type ProxyConfig struct {
@@ -88,14 +127,47 @@ type ProxyConfig struct {
 }
 
 type EndpointConfig struct {
-	Target       string `yaml:"target"`
-	ChangeOrigin bool   `yaml:"changeOrigin"`
+	Target         string            `yaml:"target"`
+	ChangeOrigin   bool              `yaml:"changeOrigin"`
+	Secure         *bool             `yaml:"secure,omitempty"`
+	Credentials    string            `yaml:"credentials,omitempty"`
+	Headers        map[string]string `yaml:"headers,omitempty"`
+	AllowedMethods []string          `yaml:"allowedMethods,omitempty"`
+	AllowedHeaders []string          `yaml:"allowedHeaders,omitempty"`
+	PathRewrite    map[string]string `yaml:"pathRewrite,omitempty"`
+}
+
+// ProxyPreset holds the suggested settings for a proxy endpoint used by one of the plugins
+type ProxyPreset struct {
+	Path           string
+	Target         string
+	Secure         bool
+	Credentials    string
+	AllowedMethods []string
+}
+
+var proxyPresets = map[string]ProxyPreset{
+	"scaleops": {
+		Path:           "/scaleops",
+		Target:         "https://scaleops.example.com",
+		Secure:         true,
+		Credentials:    "require",
+		AllowedMethods: []string{"GET", "POST"},
+	},
+	"vcf-automation": {
+		Path:           "/vcf-automation",
+		Target:         "https://vcf-automation.example.com",
+		Secure:         true,
+		Credentials:    "require",
+		AllowedMethods: []string{"GET", "POST", "PATCH", "DELETE"},
+	},
 }
 
 type TechdocsConfig struct {
-	Builder   string           `yaml:"builder"`
-	Generator GeneratorConfig  `yaml:"generator"`
-	Publisher PublisherConfig `yaml:"publisher"`
+	Builder   string               `yaml:"builder"`
+	Generator *GeneratorConfig     `yaml:"generator,omitempty"`
+	Publisher PublisherConfig      `yaml:"publisher"`
+	Cache     *TechdocsCacheConfig `yaml:"cache,omitempty"`
 }
 
 type GeneratorConfig struct {
@@ -103,7 +175,43 @@ type GeneratorConfig struct {
 }
 
 type PublisherConfig struct {
-	Type string `yaml:"type"`
+	Type             string                  `yaml:"type"`
+	AwsS3            *AwsS3PublisherConfig   `yaml:"awsS3,omitempty"`
+	GoogleGcs        *GcsPublisherConfig     `yaml:"googleGcs,omitempty"`
+	AzureBlobStorage *AzureBlobPublisherConfig `yaml:"azureBlobStorage,omitempty"`
+}
+
+type AwsS3PublisherConfig struct {
+	BucketName       string            `yaml:"bucketName"`
+	Region           string            `yaml:"region,omitempty"`
+	Endpoint         string            `yaml:"endpoint,omitempty"`
+	S3ForcePathStyle bool              `yaml:"s3ForcePathStyle,omitempty"`
+	Credentials      AwsS3Credentials `yaml:"credentials"`
+}
+
+type AwsS3Credentials struct {
+	AccessKeyId     string `yaml:"accessKeyId" secret:"true"`
+	SecretAccessKey string `yaml:"secretAccessKey" secret:"true"`
+}
+
+type GcsPublisherConfig struct {
+	BucketName  string `yaml:"bucketName"`
+	Credentials string `yaml:"credentials,omitempty" secret:"true"`
+}
+
+type AzureBlobPublisherConfig struct {
+	ContainerName string               `yaml:"containerName"`
+	Credentials   AzureBlobCredentials `yaml:"credentials"`
+}
+
+type AzureBlobCredentials struct {
+	AccountName string `yaml:"accountName"`
+	AccountKey  string `yaml:"accountKey,omitempty" secret:"true"`
+}
+
+type TechdocsCacheConfig struct {
+	TTL         int `yaml:"ttl"`
+	ReadTimeout int `yaml:"readTimeout,omitempty"`
 }
 
 type AuthConfig struct {
@@ -112,6 +220,14 @@ type AuthConfig struct {
 }
 
 type ScaffolderConfig struct {
+	DefaultAuthor        *ScaffolderAuthorConfig `yaml:"defaultAuthor,omitempty"`
+	DefaultCommitMessage string                  `yaml:"defaultCommitMessage,omitempty"`
+	ConcurrentTasksLimit int                     `yaml:"concurrentTasksLimit,omitempty"`
+}
+
+type ScaffolderAuthorConfig struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
 }
 
 type CatalogConfig struct {
@@ -127,7 +243,7 @@ type CatalogProvidersConfig struct {
 
 type MSGraphConfig struct {
 	ClientId     string              `yaml:"clientId"`
-	ClientSecret string              `yaml:"clientSecret"`
+	ClientSecret string              `yaml:"clientSecret" secret:"true"`
 	TenantId     string              `yaml:"tenantId"`
 	User         MSGraphUserConfig   `yaml:"user"`
 	Schedule     MSGraphScheduleConfig `yaml:"schedule"`
@@ -161,6 +277,11 @@ type KubernetesIngestorConfig struct {
 	Mappings   MappingsConfig   `yaml:"mappings"`
 	Components ComponentsConfig `yaml:"components"`
 	Crossplane CrossplaneIngestorConfig `yaml:"crossplane"`
+	GenericCRDTemplates *GenericCRDTemplatesConfig `yaml:"genericCRDTemplates,omitempty"`
+}
+
+type GenericCRDTemplatesConfig struct {
+	PublishPhase PublishPhaseConfig `yaml:"publishPhase"`
 }
 
 type MappingsConfig struct {
@@ -210,9 +331,17 @@ type CrossplaneXrdsConfig struct {
 
 type PublishPhaseConfig struct {
 	AllowRepoSelection bool     `yaml:"allowRepoSelection"`
-	AllowedTargets     []string `yaml:"allowedTargets"`
+	AllowedTargets     []string `yaml:"allowedTargets,omitempty"`
 	Target             string   `yaml:"target"`
-	Git                GitConfig `yaml:"git"`
+	Git                GitConfig `yaml:"git,omitempty"`
+}
+
+// publishTargetHosts are the default git hosts for each publish phase target
+var publishTargetHosts = map[string]string{
+	"github":         "github.com",
+	"gitlab":         "gitlab.com",
+	"bitbucket":      "",
+	"bitbucketCloud": "bitbucket.org",
 }
 
 type GitConfig struct {
@@ -239,16 +368,33 @@ type ServiceLocatorMethodConfig struct {
 }
 
 type ClusterLocatorMethodConfig struct {
-	Type     string          `yaml:"type"`
-	Clusters []ClusterConfig `yaml:"clusters"`
+	Type              string          `yaml:"type"`
+	Clusters          []ClusterConfig `yaml:"clusters,omitempty"`
+	ProjectId         string          `yaml:"projectId,omitempty"`
+	Region            string          `yaml:"region,omitempty"`
+	SkipTLSVerify     bool            `yaml:"skipTLSVerify,omitempty"`
+	SkipMetricsLookup bool            `yaml:"skipMetricsLookup,omitempty"`
+	ExposeDashboard   bool            `yaml:"exposeDashboard,omitempty"`
 }
 
 type ClusterConfig struct {
-	Name               string `yaml:"name"`
-	Url                string `yaml:"url"`
-	AuthProvider       string `yaml:"authProvider"`
-	ServiceAccountToken string `yaml:"serviceAccountToken"`
-	SkipTLSVerify      bool   `yaml:"skipTLSVerify"`
+	Name                string                 `yaml:"name"`
+	Url                 string                 `yaml:"url"`
+	AuthProvider        string                 `yaml:"authProvider"`
+	ServiceAccountToken string                 `yaml:"serviceAccountToken,omitempty" secret:"true"`
+	OidcTokenProvider   string                 `yaml:"oidcTokenProvider,omitempty"`
+	SkipTLSVerify       bool                   `yaml:"skipTLSVerify,omitempty"`
+	CAData              string                 `yaml:"caData,omitempty"`
+	CAFile              string                 `yaml:"caFile,omitempty"`
+	DashboardApp        string                 `yaml:"dashboardApp,omitempty"`
+	DashboardUrl        string                 `yaml:"dashboardUrl,omitempty"`
+	CustomResources     []CustomResourceConfig `yaml:"customResources,omitempty"`
+}
+
+type CustomResourceConfig struct {
+	Group      string `yaml:"group"`
+	ApiVersion string `yaml:"apiVersion"`
+	Plural     string `yaml:"plural"`
 }
 
 type ScaleopsConfig struct {
@@ -295,6 +441,35 @@ type DevpodConfig struct {
 	DefaultIDE string `yaml:"defaultIDE"`
 }
 
+type VcfAutomationConfig struct {
+	EnablePermissions bool                    `yaml:"enablePermissions"`
+	Instances         []VcfAutomationInstance `yaml:"instances"`
+}
+
+type VcfAutomationInstance struct {
+	Name           string                  `yaml:"name"`
+	BaseUrl        string                  `yaml:"baseUrl"`
+	MajorVersion   int                     `yaml:"majorVersion"`
+	OrgName        string                  `yaml:"orgName,omitempty"`
+	Authentication VcfAuthenticationConfig `yaml:"authentication"`
+}
+
+type VcfAuthenticationConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password" secret:"true"`
+	Domain   string `yaml:"domain,omitempty"`
+}
+
+// promptInput is where answers are read from, tests replace it with canned answers
+var promptInput = bufio.NewReader(os.Stdin)
+
+// readLine reads one answer, without surrounding whitespace. At the end of the
+// input it returns an empty answer, which accepts the default.
+func readLine() string {
+	line, _ := promptInput.ReadString('\n')
+	return strings.TrimSpace(line)
+}
+
 // Helper functions for prompting
 func promptString(prompt string, defaultVal string) string {
 	if defaultVal != "" {
@@ -302,470 +477,88 @@ func promptString(prompt string, defaultVal string) string {
 	} else {
 		fmt.Printf("%s: ", prompt)
 	}
-	var input string
-	fmt.Scanln(&input)
+	input := readLine()
 	if input == "" {
 		return defaultVal
 	}
 	return input
 }
 
-func promptBool(prompt string, defaultVal bool) bool {
+// promptRepeat asks a yes/no question outside of the sections, like confirming a write
+func promptRepeat(prompt string, defaultVal bool) bool {
+	if tuiEnabled {
+		defaultIndex := 1
+		if defaultVal {
+			defaultIndex = 0
+		}
+		return selectOption(prompt, []string{"Yes", "No"}, defaultIndex) == 0
+	}
 	defaultStr := "n"
 	if defaultVal {
 		defaultStr = "y"
 	}
 	fmt.Printf("%s (y/n) [%s]: ", prompt, defaultStr)
-	var input string
-	fmt.Scanln(&input)
+	input := readLine()
 	if input == "" {
 		return defaultVal
 	}
 	return input == "y" || input == "Y"
 }
 
-func promptStringSlice(prompt string, defaultVals []string) []string {
-	if len(defaultVals) > 0 {
-		fmt.Printf("%s [%s]: ", prompt, strings.Join(defaultVals, ","))
-	} else {
-		fmt.Printf("%s (comma-separated): ", prompt)
-	}
-	var input string
-	fmt.Scanln(&input)
-	if input == "" {
-		return defaultVals
-	}
-	return strings.Split(input, ",")
-}
-
-// Configuration getter functions
-func getAppConfig() AppConfig {
-	fmt.Println("")
-	fmt.Println("General App Configurations")
-	fmt.Println("==========================")
-	return AppConfig{
-		Title:   promptString("Enter application title", "TeraSky OSS Backstage"),
-		BaseUrl: promptString("Enter frontend base URL", "http://localhost:3000"),
-	}
-}
-
-func getBackendConfig() BackendConfig {
-	fmt.Println("")
-	fmt.Println("Backend Configurations")
-	fmt.Println("=====================")
-	port := promptString("Enter backend port", "7007")
-	baseUrl := promptString("Enter backend base URL", fmt.Sprintf("http://localhost:%s", port))
-
-	return BackendConfig{
-		BaseUrl: baseUrl,
-		Listen: ListenConfig{
-			Port: port,
-		},
-		CSP: CSPConfig{
-			ConnectSrc: []string{"'self'", "http:", "https:"},
-		},
-		CORS: CORSConfig{
-			Origin:      "http://localhost:3000",
-			Methods:     []string{"GET", "HEAD", "PATCH", "POST", "PUT", "DELETE"},
-			Credentials: true,
-		},
-		Database: DatabaseConfig{
-			Client:     "better-sqlite3",
-			Connection: ":memory:",
-		},
-		Reading: ReadingConfig{
-			Allow: []AllowConfig{
-				{Host: "raw.githubusercontent.com"},
-			},
-		},
-	}
-}
-
-func getGithubIntegrationConfig() IntegrationsConfig {
-	fmt.Println("")
-	fmt.Println("Source Control Integration Configurations")
-	fmt.Println("========================================")
-	if !promptBool("Configure GitHub integration?", true) {
-		return IntegrationsConfig{}
-	}
-
-	token := promptString("Enter GitHub PAT", "")
-	return IntegrationsConfig{
-		Github: []GithubIntegrationConfig{
-			{
-				Host:  "github.com",
-				Token: token,
-			},
-		},
-	}
-}
-
-func getAuthConfig() AuthConfig {
-	fmt.Println("")
-	fmt.Println("Authentication Configurations")
-	fmt.Println("===========================")
-	providers := make(map[string]interface{})
-
-	if promptBool("Configure Microsoft authentication?", false) {
-		providers["microsoft"] = map[string]interface{}{
-			"development": map[string]interface{}{
-				"clientId":     promptString("Enter Microsoft client ID", ""),
-				"clientSecret": promptString("Enter Microsoft client secret", ""),
-				"tenantId":     promptString("Enter Microsoft tenant ID", ""),
-				"domainHint":   promptString("Enter Microsoft domain hint", ""),
-			},
-		}
-	}
-
-	if promptBool("Configure GitHub authentication?", false) {
-		providers["github"] = map[string]interface{}{
-			"development": map[string]interface{}{
-				"clientId":     promptString("Enter GitHub client ID", ""),
-				"clientSecret": promptString("Enter GitHub client secret", ""),
-			},
-		}
-	}
-
-	return AuthConfig{
-		Environment: "development",
-		Providers:   providers,
-	}
-}
-
-func getCatalogConfig() CatalogConfig {
-	fmt.Println("")
-	fmt.Println("Catalog Configurations")
-	fmt.Println("=====================")
-	msGraphConfig := make(map[string]MSGraphConfig)
-
-	if promptBool("Configure Microsoft Graph integration?", false) {
-		msGraphConfig["default"] = MSGraphConfig{
-			ClientId:     promptString("Enter Microsoft Graph client ID", ""),
-			ClientSecret: promptString("Enter Microsoft Graph client secret", ""),
-			TenantId:     promptString("Enter Microsoft Graph tenant ID", ""),
-			User: MSGraphUserConfig{
-				Filter: "accountEnabled eq true and userType eq 'member'",
-			},
-			Schedule: MSGraphScheduleConfig{
-				Frequency: "PT1H",
-				Timeout:   "PT50M",
-			},
-		}
-	}
-
-	return CatalogConfig{
-		Providers: CatalogProvidersConfig{
-			MicrosoftGraphOrg: msGraphConfig,
-		},
-		Import: ImportConfig{
-			EntityFilename:         "catalog-info.yaml",
-			PullRequestBranchName: "backstage-integration",
-		},
-		Rules: []RuleConfig{
-			{Allow: []string{"Component", "System", "API", "Resource", "Location", "Template"}},
-		},
-		Locations: []LocationConfig{
-			{
-				Type:   "file",
-				Target: "../../examples/entities.yaml",
-			},
-			{
-				Type:   "file",
-				Target: "../../examples/template/template.yaml",
-				Rules:  []RuleConfig{{Allow: []string{"Template"}}},
-			},
-			{
-				Type:   "file",
-				Target: "../../examples/org.yaml",
-				Rules:  []RuleConfig{{Allow: []string{"User", "Group"}}},
-			},
-		},
-	}
-}
-
-func getKubernetesConfig() *KubernetesConfig {
-	fmt.Println("")
-	fmt.Println("Kubernetes Configurations")
-	fmt.Println("=========================")
-	if !promptBool("Configure Kubernetes integration?", false) {
-		return nil
+// promptMultiSelect lists options by number and returns the indexes the user picked
+func promptMultiSelect(prompt string, options []string) []int {
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option)
 	}
+	fmt.Printf("%s (comma-separated numbers or 'all') [all]: ", prompt)
+	input := readLine()
 
-	var clusters []ClusterConfig
-	for promptBool("Add a Kubernetes cluster?", true) {
-		cluster := ClusterConfig{
-			Name:          promptString("Enter cluster name", ""),
-			Url:           promptString("Enter cluster URL", ""),
-			AuthProvider:  promptString("Enter auth provider (serviceAccount/oidc)", "serviceAccount"),
-			SkipTLSVerify: promptBool("Skip TLS verification?", false),
+	var selected []int
+	if input == "" || input == "all" {
+		for i := range options {
+			selected = append(selected, i)
 		}
-
-		if cluster.AuthProvider == "serviceAccount" {
-			cluster.ServiceAccountToken = promptString("Enter service account token", "")
-		}
-
-		clusters = append(clusters, cluster)
-	}
-
-	return &KubernetesConfig{
-		Frontend: K8sFrontendConfig{
-			PodDelete: PodDeleteConfig{
-				Enabled: true,
-			},
-		},
-		ServiceLocatorMethod: ServiceLocatorMethodConfig{
-			Type: "multiTenant",
-		},
-		ClusterLocatorMethods: []ClusterLocatorMethodConfig{
-			{
-				Type:     "config",
-				Clusters: clusters,
-			},
-		},
-	}
-}
-
-func getKubernetesIngestorConfig() *KubernetesIngestorConfig {
-	fmt.Println("")
-	fmt.Println("Kubernetes Ingestor Configurations")
-	fmt.Println("==================================")
-	if !promptBool("Configure Kubernetes Ingestor?", false) {
-		return nil
+		return selected
 	}
-	fmt.Println("")
-	fmt.Println("Kubernetes To Backstage Mappings Configurations")
-	fmt.Println("================================================")
-	mappings := MappingsConfig{
-		NamespaceModel:           promptString("Enter namespace model (cluster/namespace/default)", "default"),
-		NameModel:                promptString("Enter name model (name-cluster/name-namespace/name)", "name-cluster"),
-		TitleModel:               promptString("Enter title model (name/name-cluster/name-namespace)", "name"),
-		SystemModel:              promptString("Enter system model (cluster/namespace/cluster-namespace/default)", "cluster-namespace"),
-		ReferencesNamespaceModel: promptString("Enter references namespace model (default/same)", "default"),
-	}
-	fmt.Println("")
-	fmt.Println("Kubernetes Workloads Component Generation Configurations")
-	fmt.Println("=========================================================")
-	components := ComponentsConfig{
-		Enabled: promptBool("Enable components?", true),
-		TaskRunner: TaskRunnerConfig{
-			Frequency: 10,
-			Timeout:   600,
-		},
-		ExcludedNamespaces:          promptStringSlice("Enter excluded namespaces", []string{"kube-public", "kube-system", "default"}),
-		DisableDefaultWorkloadTypes: promptBool("Disable default workload types?", false),
-		OnlyIngestAnnotatedResources: promptBool("Only ingest annotated resources?", false),
-	}
-	fmt.Println("")
-	fmt.Println("Custom Workload Types Configurations")
-	fmt.Println("==================================")
-	if promptBool("Add custom workload types?", false) {
-		for promptBool("Add another custom workload type?", true) {
-			components.CustomWorkloadTypes = append(components.CustomWorkloadTypes, CustomWorkloadType{
-				Group:      promptString("Enter group", ""),
-				ApiVersion: promptString("Enter API version", ""),
-				Plural:     promptString("Enter plural", ""),
-			})
+	for _, field := range strings.Split(input, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 || n > len(options) {
+			fmt.Printf("Ignoring invalid selection: %s\n", field)
+			continue
 		}
+		selected = append(selected, n-1)
 	}
-	fmt.Println("")
-	fmt.Println("Crossplane Ingestion Configurations")
-	fmt.Println("====================================")
-	crossplane := CrossplaneIngestorConfig{
-		Claims: CrossplaneClaimsConfig{
-			IngestAllClaims: promptBool("Ingest all claims?", true),
-		},
-		Xrds: CrossplaneXrdsConfig{
-			ConvertDefaultValuesToPlaceholders: promptBool("Convert default values to placeholders?", true),
-			Enabled:                           promptBool("Enable XRDs?", true),
-			IngestAllXRDs:                     promptBool("Ingest all XRDs?", true),
-			TaskRunner: TaskRunnerConfig{
-				Frequency: 10,
-				Timeout:   600,
-			},
-			PublishPhase: PublishPhaseConfig{
-				AllowRepoSelection: promptBool("Allow repo selection?", false),
-				AllowedTargets:     promptStringSlice("Enter allowed targets", []string{"github.com", "gitlab.com"}),
-				Target:             promptString("Enter target", "github"),
-				Git: GitConfig{
-					RepoUrl:      promptString("Enter Git repo URL", "github.com?owner=vrabbi-tap&repo=acc-v2-poc"),
-					TargetBranch: promptString("Enter target branch", "main"),
-				},
-			},
-		},
-	}
-
-	return &KubernetesIngestorConfig{
-		Mappings:   mappings,
-		Components: components,
-		Crossplane: crossplane,
-	}
-}
-
-func getScaleopsConfig() *ScaleopsConfig {
-	fmt.Println("")
-	fmt.Println("ScaleOps Configurations")
-	fmt.Println("=====================")
-	if !promptBool("Configure ScaleOps?", false) {
-		return nil
-	}
-
-	return &ScaleopsConfig{
-		BaseUrl:         promptString("Enter ScaleOps base URL", "http://scaleops.10.100.148.235.nip.io"),
-		CurrencyPrefix:  promptString("Enter currency prefix", "$"),
-		LinkToDashboard: promptBool("Enable dashboard linking?", true),
-		Authentication: AuthenticationConfig{
-			Enabled: promptBool("Enable authentication?", false),
-		},
-	}
-}
-
-func getProxyConfig() *ProxyConfig {
-	fmt.Println("")
-	fmt.Println("Backstage Backend Proxy Configurations")
-	fmt.Println("========================================")
-	if !promptBool("Configure proxy endpoints?", false) {
-		return nil
-	}
-
-	endpoints := make(map[string]EndpointConfig)
-	for promptBool("Add proxy endpoint?", true) {
-		path := promptString("Enter endpoint path (e.g., /scaleops)", "/scaleops")
-		endpoints[path] = EndpointConfig{
-			Target:       promptString("Enter target URL", ""),
-			ChangeOrigin: promptBool("Enable change origin?", true),
-		}
-	}
-
-	return &ProxyConfig{
-		Endpoints: endpoints,
-	}
-}
-
-func getCrossplaneConfig() *CrossplaneConfig {
-	fmt.Println("")
-	fmt.Println("Crossplane Visualization Configurations")
-	fmt.Println("========================================")
-	if !promptBool("Configure Crossplane?", false) {
-		return nil
-	}
-	return &CrossplaneConfig{
-		EnablePermissions: promptBool("Enable Crossplane permissions?", true),
-	}
-}
-
-func getKyvernoConfig() *KyvernoConfig {
-	fmt.Println("")
-	fmt.Println("Kyverno Policy Report Configurations")
-	fmt.Println("========================================")
-	if !promptBool("Configure Kyverno?", false) {
-		return nil
-	}
-	return &KyvernoConfig{
-		EnablePermissions: promptBool("Enable Kyverno permissions?", true),
-	}
-}
-
-func getDetailedPermissionConfig() PermissionConfig {
-	fmt.Println("")
-	fmt.Println("Permission Framework Configurations")
-	fmt.Println("========================================================")
-	if !promptBool("Configure permissions?", false) {
-		return PermissionConfig{}
-	}
-	fmt.Println("")
-	fmt.Println("RBAC Plugin Configurations")
-	fmt.Println("==========================")
-	rbac := RbacPermissionConfig{
-		PoliciesCSVFile:  promptString("Enter policies CSV file path", "/home/vrabbi/crossplane/bakstage-plugins/permissions.csv"),
-		PolicyFileReload: promptBool("Enable policy file reload?", true),
-		PluginsWithPermission: promptStringSlice("Enter plugins with permission", []string{
-			"catalog", "permission", "kubernetes", "crossplane", "scaffolder", "kyverno",
-		}),
-	}
-
-	// Admin users
-	fmt.Println("\nConfiguring admin users:")
-	var adminUsers []UserConfig
-	for promptBool("Add admin user?", true) {
-		adminUsers = append(adminUsers, UserConfig{
-			Name: promptString("Enter admin user name (e.g., user:default/username)", ""),
-		})
-	}
-	rbac.Admin = AdminConfig{Users: adminUsers}
-
-	// Super admin users
-	fmt.Println("\nConfiguring super admin users:")
-	var superAdminUsers []UserConfig
-	for promptBool("Add super admin user?", true) {
-		superAdminUsers = append(superAdminUsers, UserConfig{
-			Name: promptString("Enter super admin user name (e.g., user:default/username)", ""),
-		})
-	}
-	rbac.SuperAdmin = AdminConfig{Users: superAdminUsers}
-
-	return PermissionConfig{
-		Enabled: true,
-		Rbac:    rbac,
-	}
-}
-
-func getDevpodConfig() *DevpodConfig {
-	fmt.Println("")
-	fmt.Println("Devpod Configurations")
-	fmt.Println("=====================")
-	if !promptBool("Configure Devpod?", false) {
-		return nil
-	}
-
-	return &DevpodConfig{
-		DefaultIDE: promptString("Enter default IDE", "webstorm"),
-	}
+	return selected
 }
 
 func main() {
-	outputFile := flag.String("output", "", "Output file path (defaults to stdout)")
-	flag.Parse()
-
-	config := Config{
-		App:                getAppConfig(),
-		Organization:       OrgConfig{Name: promptString("Enter organization name", "TeraSky")},
-		Backend:           getBackendConfig(),
-		Auth:              getAuthConfig(),
-		Integrations:      getGithubIntegrationConfig(),
-		Catalog:           getCatalogConfig(),
-		Techdocs: TechdocsConfig{
-			Builder: "local",
-			Generator: GeneratorConfig{
-				RunIn: "docker",
-			},
-			Publisher: PublisherConfig{
-				Type: "local",
-			},
-		},
-		Kubernetes:        getKubernetesConfig(),
-		KubernetesIngestor: getKubernetesIngestorConfig(),
-		Scaleops:          getScaleopsConfig(),
-		Proxy:             getProxyConfig(),
-		Devpod:           getDevpodConfig(),
-		Permission:        getDetailedPermissionConfig(),
-		Crossplane:        getCrossplaneConfig(),
-		Kyverno:           getKyvernoConfig(),
-	}
-
-	yamlData, err := yaml.Marshal(&config)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling YAML: %v\n", err)
+	command, args := "", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "":
+		runWizard(args)
+	case "presets":
+		runPresets(args)
+	case "diff":
+		runDiff(args)
+	case "migrate":
+		runMigrate(args)
+	case "doctor":
+		runDoctor(args)
+	case "preview-ingest":
+		runPreviewIngest(args)
+	case "schema":
+		runSchema(args)
+	case "explain":
+		runExplain(args)
+	case "help":
+		printUsage(os.Stdout)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", command)
+		printUsage(os.Stderr)
 		os.Exit(1)
 	}
-
-	if *outputFile == "" {
-		fmt.Println(string(yamlData))
-	} else {
-		err := os.WriteFile(*outputFile, yamlData, 0644)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
-			os.Exit(1)
-		}
-		fmt.Printf("Configuration written to %s\n", *outputFile)
-	}
 }
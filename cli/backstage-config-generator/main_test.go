@@ -0,0 +1,1229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "Regenerate the golden files in testdata")
+
+// answersReader feeds canned answers to the prompts. Prompts accept their default
+// once the answers run out, so a prompt loop that never ends is reported instead
+// of hanging the test.
+type answersReader struct {
+	r        io.Reader
+	eofReads int
+}
+
+type answersExhausted struct{}
+
+func (a *answersReader) Read(p []byte) (int, error) {
+	n, err := a.r.Read(p)
+	if err == io.EOF {
+		a.eofReads++
+		if a.eofReads > 100 {
+			panic(answersExhausted{})
+		}
+	}
+	return n, err
+}
+
+// readAnswers reads an answers file, one answer per line. Empty lines accept the
+// default and lines starting with "#" are comments.
+func readAnswers(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var answers []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			answers = append(answers, line)
+		}
+	}
+	return strings.Join(answers, "\n") + "\n"
+}
+
+// runWithAnswers runs fn with the prompts reading from answers and their output discarded
+func runWithAnswers(t *testing.T, answers string, fn func()) {
+	t.Helper()
+	var err error
+	defaults, err = loadDefaults("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuiEnabled = false
+
+	stdout := os.Stdout
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	promptInput = bufio.NewReader(&answersReader{r: strings.NewReader(answers)})
+	defer func() { promptInput = bufio.NewReader(os.Stdin) }()
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(answersExhausted); ok {
+					t.Fatal("prompts kept asking after the answers ran out")
+				}
+				panic(r)
+			}
+		}()
+		fn()
+	}()
+
+	if rest, _ := io.ReadAll(promptInput); strings.TrimSpace(string(rest)) != "" {
+		t.Errorf("answers were not used: %q", rest)
+	}
+}
+
+func checkGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update after checking the change)\n--- got\n%s", path, got)
+	}
+}
+
+func findSection(t *testing.T, sections []Section, title string) Section {
+	t.Helper()
+	for _, section := range sections {
+		if section.Title == title {
+			return section
+		}
+	}
+	t.Fatalf("no section titled %q", title)
+	return Section{}
+}
+
+func TestSections(t *testing.T) {
+	tests := []struct {
+		name       string
+		section    string
+		kubeconfig string
+	}{
+		{"general-app", "General App", ""},
+		{"backend-sqlite", "Backend", ""},
+		{"backend-postgres", "Backend", ""},
+		{"authentication", "Authentication", ""},
+		{"source-control", "Source Control Integration", ""},
+		{"catalog", "Catalog", ""},
+		{"techdocs-local", "TechDocs", ""},
+		{"techdocs-s3", "TechDocs", ""},
+		{"kubernetes", "Kubernetes", ""},
+		{"kubernetes-kubeconfig", "Kubernetes", "testdata/kubeconfig.yaml"},
+		{"kubernetes-ingestor", "Kubernetes Ingestor", ""},
+		{"scaleops", "ScaleOps", ""},
+		{"proxy", "Backend Proxy", ""},
+		{"devpod", "Devpod", ""},
+		{"permission", "Permission Framework", ""},
+		{"crossplane", "Crossplane", ""},
+		{"kyverno", "Kyverno", ""},
+		{"vcf-automation", "VCF Automation", ""},
+		{"scaffolder", "Scaffolder", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activePreset = nil
+			section := findSection(t, configSections(tt.kubeconfig), tt.section)
+			answers := readAnswers(t, filepath.Join("testdata", "sections", tt.name+".answers"))
+
+			var config Config
+			runWithAnswers(t, answers, func() {
+				if err := section.Run(&config); err != nil {
+					t.Fatal(err)
+				}
+			})
+
+			got, err := marshalConfig(&config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Join("testdata", "sections", tt.name+".golden.yaml"), got)
+		})
+	}
+}
+
+// presetConfig runs every section with a preset and its scripted answers
+func presetConfig(t *testing.T, name string) *Config {
+	t.Helper()
+	preset, err := lookupPreset(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activePreset = preset
+	defer func() { activePreset = nil }()
+	answers := readAnswers(t, filepath.Join("testdata", "presets", name+".answers"))
+
+	var config Config
+	runWithAnswers(t, answers, func() {
+		for _, section := range configSections("") {
+			if err := section.Run(&config); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	return &config
+}
+
+func TestPresets(t *testing.T) {
+	for name := range presets {
+		t.Run(name, func(t *testing.T) {
+			config := presetConfig(t, name)
+			got, err := marshalConfig(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Join("testdata", "presets", fmt.Sprintf("%s.golden.yaml", name)), got)
+		})
+	}
+}
+
+func TestEmitters(t *testing.T) {
+	emitters := map[string]func(config *Config) ([]byte, error){
+		"k8s":  renderK8sManifests,
+		"helm": renderHelmValues,
+		"compose": func(config *Config) ([]byte, error) {
+			stack, err := renderComposeStack(config, "../..")
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			for _, name := range stack.Names {
+				fmt.Fprintf(&buf, "# %s\n%s", name, stack.Files[name])
+			}
+			return buf.Bytes(), nil
+		},
+	}
+	for _, preset := range []string{"minimal", "crossplane-platform", "full"} {
+		for emit, render := range emitters {
+			t.Run(preset+"-"+emit, func(t *testing.T) {
+				got, err := render(presetConfig(t, preset))
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkGolden(t, filepath.Join("testdata", "emit", preset+"."+emit+".golden.yaml"), got)
+			})
+		}
+	}
+}
+
+func TestPresetDefaultsNameQuestions(t *testing.T) {
+	known := make(map[string]bool)
+	var walk func(section string, questions []Question)
+	walk = func(section string, questions []Question) {
+		for _, q := range questions {
+			known[section+"."+q.ID] = true
+			walk(section, q.Questions)
+		}
+	}
+	for _, section := range configSections("") {
+		walk(section.ID, section.Questions)
+	}
+	for _, name := range presetDefaults {
+		if !known[name] {
+			t.Errorf("preset default %s does not name a question", name)
+		}
+	}
+}
+
+func TestDefaultsPassStrictAudit(t *testing.T) {
+	var config Config
+	runWithAnswers(t, strings.Repeat("\n", 200), func() {
+		for _, section := range configSections("") {
+			if err := section.Run(&config); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	for _, finding := range auditConfig(&config, false) {
+		if finding.Severity >= SeverityWarning {
+			t.Errorf("accepting every default produced [%s] %s: %s", finding.Severity, finding.Path, finding.Message)
+		}
+	}
+}
+
+func TestAuditConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		production bool
+		configure  func(config *Config)
+		want       []Finding
+	}{
+		{"clean", false, func(config *Config) {}, nil},
+		{"skipTLSVerify without a CA", false, func(config *Config) {
+			config.Kubernetes = &KubernetesConfig{ClusterLocatorMethods: []ClusterLocatorMethodConfig{{Type: "config", Clusters: []ClusterConfig{{Name: "prod", SkipTLSVerify: true}}}}}
+		}, []Finding{{Severity: SeverityCritical, Path: "kubernetes.clusterLocatorMethods[0].clusters[0].skipTLSVerify"}}},
+		{"skipTLSVerify with a CA", false, func(config *Config) {
+			config.Kubernetes = &KubernetesConfig{ClusterLocatorMethods: []ClusterLocatorMethodConfig{{Type: "config", Clusters: []ClusterConfig{{Name: "prod", SkipTLSVerify: true, CAData: "LS0t"}}}}}
+		}, []Finding{{Severity: SeverityWarning, Path: "kubernetes.clusterLocatorMethods[0].clusters[0].skipTLSVerify"}}},
+		{"wildcard CORS", false, func(config *Config) {
+			config.Backend.CORS = CORSConfig{Origin: "*"}
+		}, []Finding{{Severity: SeverityWarning, Path: "backend.cors.origin"}}},
+		{"wildcard CORS with credentials", false, func(config *Config) {
+			config.Backend.CORS = CORSConfig{Origin: "*", Credentials: true}
+		}, []Finding{{Severity: SeverityCritical, Path: "backend.cors.origin"}}},
+		{"in-memory database", false, func(config *Config) {
+			config.Backend.Database = DatabaseConfig{Client: "better-sqlite3", Connection: DatabaseConnection{Path: ":memory:"}}
+		}, []Finding{{Severity: SeverityInfo, Path: "backend.database.connection"}}},
+		{"in-memory database in production", true, func(config *Config) {
+			config.Backend.Database = DatabaseConfig{Client: "better-sqlite3", Connection: DatabaseConnection{Path: ":memory:"}}
+		}, []Finding{{Severity: SeverityCritical, Path: "backend.database.connection"}}},
+		{"permission without admins", false, func(config *Config) {
+			config.Permission.Enabled = true
+		}, []Finding{{Severity: SeverityCritical, Path: "permission.rbac"}}},
+		{"permission with an admin", false, func(config *Config) {
+			config.Permission.Enabled = true
+			config.Permission.Rbac.Admin.Users = []UserConfig{{Name: "user:default/alice"}}
+		}, nil},
+		{"plaintext secret", false, func(config *Config) {
+			config.Backend.Database = DatabaseConfig{Client: "pg", Connection: DatabaseConnection{Password: "hunter2"}}
+		}, []Finding{{Severity: SeverityCritical, Path: "backend.database.connection.password"}}},
+		{"secret placeholder", false, func(config *Config) {
+			config.Backend.Database = DatabaseConfig{Client: "pg", Connection: DatabaseConnection{Password: "${POSTGRES_PASSWORD}"}}
+		}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var config Config
+			tt.configure(&config)
+			var got []Finding
+			for _, finding := range auditConfig(&config, tt.production) {
+				got = append(got, Finding{Severity: finding.Severity, Path: finding.Path})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditExitCode(t *testing.T) {
+	tests := []struct {
+		highest Severity
+		strict  bool
+		want    int
+	}{
+		{SeverityInfo, true, 0},
+		{SeverityWarning, true, 2},
+		{SeverityCritical, true, 2},
+		{SeverityCritical, false, 0},
+	}
+	for _, tt := range tests {
+		if got := auditExitCode(tt.highest, tt.strict); got != tt.want {
+			t.Errorf("auditExitCode(%s, %v) = %d, want %d", tt.highest, tt.strict, got, tt.want)
+		}
+	}
+}
+
+func TestAnswersFile(t *testing.T) {
+	var err error
+	providedAnswers, err = loadAnswers(filepath.Join("testdata", "answers.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { providedAnswers = nil }()
+	activePreset = nil
+
+	var config Config
+	runWithAnswers(t, "", func() {
+		for _, section := range configSections("") {
+			if err := section.Run(&config); err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+
+	got, err := marshalConfig(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, filepath.Join("testdata", "answers.golden.yaml"), got)
+}
+
+func TestInvalidAnswersFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.yaml")
+	if err := os.WriteFile(path, []byte("backend:\n  database: mysql\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var err error
+	providedAnswers, err = loadAnswers(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { providedAnswers = nil }()
+
+	var config Config
+	runWithAnswers(t, "", func() { err = backendSection.Run(&config) })
+	if err == nil || !strings.Contains(err.Error(), "answers file at backend.database") {
+		t.Errorf("got %v, want an error pointing at backend.database", err)
+	}
+	if config.Backend.Database.Client != "" {
+		t.Errorf("the section was built from an invalid answers file: %+v", config.Backend)
+	}
+}
+
+func TestHelpSkipsRootKeys(t *testing.T) {
+	for key, help := range loadKeyHelp() {
+		if !strings.Contains(key, ".") && help.Description != "" {
+			t.Errorf("help.yaml describes the root key %s as %q", key, help.Description)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	var err error
+	defaults, err = loadDefaults("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := explain(&out, "kubernetesIngestor.mappings.nameModel"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"name-cluster", "name-namespace", "Default: name-cluster", "nameModel: name-cluster"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("explain output is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	if err := explain(io.Discard, "nameModel"); err == nil || !strings.Contains(err.Error(), "kubernetesIngestor.mappings.nameModel") {
+		t.Errorf("expected a suggestion for an unqualified key, got %v", err)
+	}
+}
+
+func TestHelpAnswerAsksAgain(t *testing.T) {
+	activePreset = nil
+	section := findSection(t, configSections(""), "General App")
+	var config Config
+	runWithAnswers(t, "?\nDemo\n\n\n", func() {
+		if err := section.Run(&config); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if config.App.Title != "Demo" {
+		t.Errorf("expected the title answered after the help, got %q", config.App.Title)
+	}
+}
+
+func TestBitbucketServerHostIsRequired(t *testing.T) {
+	a := newAnswers(nil)
+	a.values["publishTarget"] = "bitbucket"
+	host := &publishPhaseQuestions[1].Questions[0]
+	var got string
+	var err error
+	runWithAnswers(t, "\n\nbitbucket.acme.io\n", func() { got, err = askQuestion(plainFrontend{}, host, a, nil) })
+	if err != nil || got != "bitbucket.acme.io" {
+		t.Errorf("expected empty hosts to be asked again, got %q", got)
+	}
+}
+
+// validateSchema checks v against the keywords renderSchema uses and returns the
+// key paths that do not match
+func validateSchema(root, schema *JSONSchema, v interface{}, path string) []string {
+	if schema.Ref != "" {
+		return validateSchema(root, root.Definitions[strings.TrimPrefix(schema.Ref, "#/definitions/")], v, path)
+	}
+	if len(schema.OneOf) > 0 {
+		matches := 0
+		for _, option := range schema.OneOf {
+			if len(validateSchema(root, option, v, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s: matches %d of the oneOf schemas", path, matches)}
+		}
+		return nil
+	}
+
+	types := []string{}
+	switch t := schema.Type.(type) {
+	case string:
+		types = append(types, t)
+	case []string:
+		types = t
+	}
+	kind := "null"
+	switch v.(type) {
+	case string:
+		kind = "string"
+	case int:
+		kind = "integer"
+	case bool:
+		kind = "boolean"
+	case []interface{}:
+		kind = "array"
+	case map[string]interface{}:
+		kind = "object"
+	}
+	if len(types) > 0 && !contains(types, kind) {
+		return []string{fmt.Sprintf("%s: %s is not %s", path, kind, strings.Join(types, " or "))}
+	}
+	if len(schema.Enum) > 0 {
+		var values []string
+		for _, value := range schema.Enum {
+			values = append(values, fmt.Sprint(value))
+		}
+		if !contains(values, fmt.Sprint(v)) {
+			return []string{fmt.Sprintf("%s: %v is not one of %v", path, v, schema.Enum)}
+		}
+	}
+
+	var problems []string
+	switch value := v.(type) {
+	case []interface{}:
+		for i, item := range value {
+			problems = append(problems, validateSchema(root, schema.Items, item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case map[string]interface{}:
+		for _, required := range schema.Required {
+			if _, ok := value[required]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: %s is required", path, required))
+			}
+		}
+		for key, item := range value {
+			property, ok := schema.Properties[key]
+			if !ok {
+				property = schema.AdditionalProperties
+			}
+			if property != nil {
+				problems = append(problems, validateSchema(root, property, item, joinPath(path, key))...)
+			}
+		}
+	}
+	return problems
+}
+
+func TestSchema(t *testing.T) {
+	var err error
+	defaults, err = loadDefaults("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := renderSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema JSONSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"KubernetesIngestorConfig", "PermissionConfig", "VcfAutomationConfig"} {
+		if schema.Definitions[name] == nil {
+			t.Errorf("schema has no definition for %s", name)
+		}
+	}
+
+	// Every configuration the generator writes must validate against its schema.
+	// The section goldens are left out, they only hold part of a configuration.
+	goldens, err := filepath.Glob(filepath.Join("testdata", "presets", "*.golden.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	goldens = append(goldens, filepath.Join("testdata", "answers.golden.yaml"))
+	// Hand-written configs such as the one of the app in this repo validate too
+	goldens = append(goldens, filepath.Join("..", "..", "app-config.yaml"))
+	for _, golden := range goldens {
+		content, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var config map[string]interface{}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			t.Fatal(err)
+		}
+		for _, problem := range validateSchema(&schema, &schema, config, "") {
+			t.Errorf("%s: %s", golden, problem)
+		}
+	}
+}
+
+func TestPreviewMapping(t *testing.T) {
+	workload := K8sObject{Kind: "StatefulSet", Metadata: K8sMetadata{Name: "orders", Namespace: "shop"}}
+	tests := []struct {
+		name     string
+		mappings MappingsConfig
+		object   K8sObject
+		want     MappingPreview
+	}{
+		{"defaults", MappingsConfig{NamespaceModel: "default", NameModel: "name", TitleModel: "name", SystemModel: "namespace", ReferencesNamespaceModel: "default"},
+			workload, MappingPreview{Ref: "component:default/orders", Title: "orders", System: "system:default/shop"}},
+		{"per cluster", MappingsConfig{NamespaceModel: "cluster", NameModel: "name-cluster", TitleModel: "name-namespace", SystemModel: "cluster-namespace", ReferencesNamespaceModel: "same"},
+			workload, MappingPreview{Ref: "component:prod/orders-prod", Title: "orders-shop", System: "system:shop/prod-shop"}},
+		{"kind suffix", MappingsConfig{NamespaceModel: "namespace", NameModel: "name-kind", TitleModel: "name-cluster", SystemModel: "cluster", ReferencesNamespaceModel: "default"},
+			workload, MappingPreview{Ref: "component:shop/orders-state", Title: "orders-prod", System: "system:default/prod"}},
+		{"annotations override", MappingsConfig{NamespaceModel: "namespace", NameModel: "name", TitleModel: "name", SystemModel: "namespace", ReferencesNamespaceModel: "default"},
+			K8sObject{Kind: "Deployment", Metadata: K8sMetadata{Name: "web", Namespace: "shop", Annotations: map[string]string{
+				"terasky.backstage.io/backstage-namespace": "team-a",
+				"terasky.backstage.io/system":              "team-a/storefront",
+			}}},
+			MappingPreview{Ref: "component:team-a/web", Title: "web", System: "system:team-a/storefront"}},
+		{"unset models", MappingsConfig{},
+			workload, MappingPreview{Ref: "component:default/orders", Title: "orders", System: "system:default/shop"}},
+		{"mixed case models", MappingsConfig{NamespaceModel: "Cluster", NameModel: "Name-Namespace", SystemModel: "Cluster"},
+			workload, MappingPreview{Ref: "component:prod/orders-shop", Title: "orders", System: "system:default/prod"}},
+		{"name too long", MappingsConfig{NameModel: "name-cluster"},
+			K8sObject{Kind: "Deployment", Metadata: K8sMetadata{Name: strings.Repeat("a", 60)}},
+			MappingPreview{Ref: "component:default/" + strings.Repeat("a", 60) + "-prod", Title: strings.Repeat("a", 60), System: "system:default/default",
+				Warning: "the name is 65 characters long, the ingestor skips entities with names over 63 characters"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := previewMapping(tt.mappings, tt.object, "prod"); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviewIngest(t *testing.T) {
+	_, config, err := loadTree(filepath.Join("testdata", "sections", "kubernetes-ingestor.golden.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects, files, err := readManifestDir(filepath.Join("testdata", "manifests"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printIngestPreview(&out, previewIngestion(config.KubernetesIngestor, objects, files, "prod"))
+	checkGolden(t, filepath.Join("testdata", "preview-ingest.golden.txt"), out.Bytes())
+}
+
+func TestPreviewIngestComponentsDisabled(t *testing.T) {
+	_, config, err := loadTree(filepath.Join("testdata", "sections", "kubernetes-ingestor.golden.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.KubernetesIngestor.Components.Enabled = false
+	objects, files, err := readManifestDir(filepath.Join("testdata", "manifests"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, preview := range previewIngestion(config.KubernetesIngestor, objects, files, "prod") {
+		switch preview.Category {
+		case "workload", "claim", "composite":
+			if preview.Skipped != "components are disabled" {
+				t.Errorf("%s %s: skipped %q, want components are disabled", preview.Object.Kind, preview.Object.Metadata.Name, preview.Skipped)
+			}
+		case "xrd":
+			if preview.Skipped == "components are disabled" {
+				t.Errorf("XRD %s skipped with the components", preview.Object.Metadata.Name)
+			}
+		}
+	}
+}
+
+func TestCRDWorkloadTypes(t *testing.T) {
+	types, defaultTypes, err := crdWorkloadTypes(filepath.Join("testdata", "crds"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, workloadType := range types {
+		got = append(got, formatWorkloadType(workloadType))
+	}
+	want := []string{"argoproj.io/v1alpha1/rollouts", "argoproj.io/v1alpha1/workflows"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("discovered %v, want %v", got, want)
+	}
+	if len(defaultTypes) != 1 || formatWorkloadType(defaultTypes[0]) != "apps/v1/deployments" {
+		t.Errorf("expected apps/v1/deployments to overlap the default types, got %v", defaultTypes)
+	}
+
+	section := newAnswers(nil)
+	section.values["discoveredWorkloadTypes"] = "argoproj.io/v1alpha1/rollouts"
+	added := newAnswers(section)
+	added.values = map[string]string{"group": "example.org", "apiVersion": "v1", "plural": "widgets"}
+	section.items["customWorkloadTypes"] = []*Answers{added}
+	for _, tt := range []struct {
+		group, apiVersion, plural string
+		valid                     bool
+	}{
+		{"example.org", "v1", "gadgets", true},
+		{"argoproj.io", "v1alpha1", "rollouts", false},
+		{"example.org", "v1", "widgets", false},
+		{"apps", "v2", "deployments", false},
+		{"example.org", "v1", "Widgets", false},
+	} {
+		item := newAnswers(section)
+		item.values = map[string]string{"group": tt.group, "apiVersion": tt.apiVersion}
+		if err := validateWorkloadPlural(tt.plural, item); (err == nil) != tt.valid {
+			t.Errorf("%s/%s/%s: got error %v, want valid %v", tt.group, tt.apiVersion, tt.plural, err, tt.valid)
+		}
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	pending, err := pendingMigrations("1.30.0", "1.41.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join("testdata", "migrate", "app-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, report, err := migrateConfig(data, pending)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printMigrationReport(&out, "1.30.0", "1.41.1", pending, report)
+	out.WriteString("---\n")
+	out.Write(migrated)
+	checkGolden(t, filepath.Join("testdata", "migrate", "app-config.golden.yaml"), out.Bytes())
+
+	// Migrating again from the target version finds nothing to do
+	pending, err = pendingMigrations("1.41.1", "1.42.0")
+	if err != nil || len(pending) != 0 {
+		t.Errorf("expected no migrations after 1.41.1, got %v %v", pending, err)
+	}
+	if _, err := pendingMigrations("1.41.1", "1.30.0"); err == nil {
+		t.Error("expected an error migrating to an older version")
+	}
+	conflicting := []byte("kubernetesIngestor:\n  claims: {}\n  crossplane:\n    claims: {}\n")
+	if _, _, err := migrateConfig(conflicting, migrations); err == nil {
+		t.Error("expected an error when both the old and the new key are set")
+	}
+}
+
+func TestMigrateCopy(t *testing.T) {
+	step := MigrationStep{Kind: MigrateCopy, From: "a.b", To: "c.b"}
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{"copies", "a:\n  b: 1\nc:\n  d: 2\n", "a:\n  b: 1\nc:\n  d: 2\n  b: 1\n"},
+		{"keeps the target", "a:\n  b: 1\nc:\n  b: 2\n", "a:\n  b: 1\nc:\n  b: 2\n"},
+		{"needs the target parent", "a:\n  b: 1\n", "a:\n  b: 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrated, _, err := migrateConfig([]byte(tt.config), []Migration{{Version: "1.0.0", Steps: []MigrationStep{step}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(migrated) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", migrated, tt.want)
+			}
+		})
+	}
+}
+
+func TestIngestorVersion(t *testing.T) {
+	tests := []struct {
+		backstage string
+		want      [3]int
+	}{
+		{"1.30.0", [3]int{}},
+		{"1.41.1", [3]int{1, 18, 1}},
+		{"1.42.0", [3]int{1, 18, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backstage, func(t *testing.T) {
+			version, err := parseVersion(tt.backstage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := ingestorVersion(version); err != nil || got != tt.want {
+				t.Errorf("got %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "backstage.json")
+	if err := os.WriteFile(path, []byte(`{"version": "1.41.1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if version, err := backstageVersion(path); err != nil || version != "1.41.1" {
+		t.Errorf("got %q, %v, want 1.41.1", version, err)
+	}
+}
+
+func TestDoctor(t *testing.T) {
+	dir := filepath.Join("testdata", "doctor")
+	plugins, err := findInstalledPlugins(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, _, err := loadTree(filepath.Join(dir, "app-config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	printDoctor(&out, plugins, checkPlugins(tree, plugins))
+	checkGolden(t, filepath.Join(dir, "doctor.golden.txt"), out.Bytes())
+}
+
+func TestSkipToReviewTakesDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		sections []Section
+		answers  string
+		check    func(t *testing.T, config *Config)
+	}{
+		{"defaults", "", []Section{appSection, backendSection},
+			// Answer the app section, skip to review and write
+			"\n\n\n4\n1\n",
+			func(t *testing.T, config *Config) {
+				if config.Backend.BaseUrl != "http://localhost:"+defaults.Backend.Port || config.Backend.Database.Client != defaults.Backend.Database {
+					t.Errorf("the skipped backend section was not built from its defaults: %+v", config.Backend)
+				}
+			}},
+		{"repeats without defaults", "crossplane-platform", []Section{appSection, permissionSection},
+			// The admin users have no defaults and are asked, the loop ends on "no"
+			"\n\n\n4\ny\nuser:default/alice\nn\nn\n1\n",
+			func(t *testing.T, config *Config) {
+				rbac := config.Permission.Rbac
+				if !config.Permission.Enabled || len(rbac.Admin.Users) != 1 || rbac.Admin.Users[0].Name != "user:default/alice" || len(rbac.SuperAdmin.Users) != 0 {
+					t.Errorf("got permission %+v, want the admin user that was asked for", config.Permission)
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.preset != "" {
+				preset, err := lookupPreset(tt.preset)
+				if err != nil {
+					t.Fatal(err)
+				}
+				activePreset = preset
+				defer func() { activePreset = nil }()
+			}
+			var config Config
+			var written bool
+			var err error
+			runWithAnswers(t, tt.answers, func() { written, err = runTUI(&config, tt.sections) })
+			if err != nil || !written {
+				t.Fatalf("the configuration was not written: %v", err)
+			}
+			tt.check(t, &config)
+		})
+	}
+}
+
+func TestEnvSource(t *testing.T) {
+	t.Setenv("BACKSTAGE_TEST_TOKEN", "s3cret")
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"set", "BACKSTAGE_TEST_TOKEN", "s3cret", false},
+		{"unset", "BACKSTAGE_TEST_MISSING", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := envSource{}.Resolve(tt.ref)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"token": "s3cret\n", "crlf": "s3cret\r\n", "multiline": "line1\nline2\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"trailing newline", filepath.Join(dir, "token"), "s3cret", false},
+		{"crlf", filepath.Join(dir, "crlf"), "s3cret", false},
+		{"multiline", filepath.Join(dir, "multiline"), "line1\nline2", false},
+		{"missing", filepath.Join(dir, "missing"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fileSource{}.Resolve(tt.ref)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVaultSource(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/backstage":
+			fmt.Fprint(w, `{"data":{"data":{"token":"kv2-token"}}}`)
+		case "/v1/kv/backstage":
+			fmt.Fprint(w, `{"data":{"token":"kv1-token"}}`)
+		case "/v1/broken/data/backstage":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	t.Setenv("VAULT_ADDR", server.URL)
+
+	tests := []struct {
+		name     string
+		token    string
+		ref      string
+		want     string
+		wantErr  bool
+		requests []string
+	}{
+		{"kv v2", "root", "secret/backstage#token", "kv2-token", false, []string{"/v1/secret/data/backstage"}},
+		{"kv v1 after a 404", "root", "kv/backstage#token", "kv1-token", false, []string{"/v1/kv/data/backstage", "/v1/kv/backstage"}},
+		{"missing key", "root", "secret/backstage#password", "", true, []string{"/v1/secret/data/backstage"}},
+		{"denied token", "wrong", "kv/backstage#token", "", true, []string{"/v1/kv/data/backstage"}},
+		{"server error", "root", "broken/backstage#token", "", true, []string{"/v1/broken/data/backstage"}},
+		{"no key", "root", "secret/backstage", "", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("VAULT_TOKEN", tt.token)
+			requests = nil
+			got, err := newVaultSource().Resolve(tt.ref)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+			if strings.Join(requests, ",") != strings.Join(tt.requests, ",") {
+				t.Errorf("requested %v, want %v", requests, tt.requests)
+			}
+		})
+	}
+}
+
+func TestUnresolvedVaultRefs(t *testing.T) {
+	config := Config{Integrations: IntegrationsConfig{Github: []GithubIntegrationConfig{{Host: "github.com", Token: "vault://secret/backstage#githubToken"}}}}
+	if _, err := marshalConfig(&config); err == nil || !strings.Contains(err.Error(), "--resolve-secrets") {
+		t.Errorf("got %v, want an error asking to resolve the vault reference", err)
+	}
+}
+
+func TestIngestionRBACWithoutCrossplane(t *testing.T) {
+	defaults, _ = loadDefaults("")
+	config := Config{KubernetesIngestor: &KubernetesIngestorConfig{}}
+	config.KubernetesIngestor.Crossplane.Claims.IngestAllClaims = true
+	var roles []string
+	for _, object := range ingestorRBAC(&config, "backstage-system") {
+		if object.Kind == "ServiceAccount" {
+			t.Error("the ServiceAccount is part of the RBAC objects, the chart would get a second one")
+		}
+		if object.RoleRef != nil {
+			roles = append(roles, object.RoleRef.Name)
+		}
+	}
+	if got, want := strings.Join(roles, ","), "view,backstage-crd-viewer,crossplane-view"; got != want {
+		t.Errorf("bound %s, want %s", got, want)
+	}
+}
+
+func TestDatabaseConnectionForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		database DatabaseConfig
+		want     string
+	}{
+		{"sqlite", DatabaseConfig{Client: "better-sqlite3", Connection: DatabaseConnection{Path: ":memory:"}}, "client: better-sqlite3\nconnection: ':memory:'\n"},
+		{"pg without host", DatabaseConfig{Client: "pg", Connection: DatabaseConnection{Port: "5432", User: "backstage", Password: "${POSTGRES_PASSWORD}"}},
+			"client: pg\nconnection:\n    host: \"\"\n    port: \"5432\"\n    user: backstage\n    password: ${POSTGRES_PASSWORD}\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yaml.Marshal(tt.database)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComposeDiffMasksEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	stack := &ComposeStack{Files: make(map[string][]byte), Secrets: make(map[string][]string)}
+	stack.add(composeEnvFileName, []byte("GITHUB_TOKEN=ghp_new\nPOSTGRES_PASSWORD=\n"), []string{"GITHUB_TOKEN"})
+	if err := os.WriteFile(filepath.Join(dir, composeEnvFileName), []byte("GITHUB_TOKEN=ghp_old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	existing := existingComposeFiles(dir, stack)
+	if len(existing) != 1 {
+		t.Fatalf("found %v, want the .env file", existing)
+	}
+	var out bytes.Buffer
+	if err := printComposeDiff(&out, dir, stack, existing); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "ghp_") || !strings.Contains(out.String(), "~ GITHUB_TOKEN: ******** -> ********") || !strings.Contains(out.String(), "+ POSTGRES_PASSWORD: \n") {
+		t.Errorf("unexpected diff:\n%s", out.String())
+	}
+}
+
+func TestDiffTrees(t *testing.T) {
+	tests := []struct {
+		name    string
+		old     string
+		updated string
+		want    []DiffEntry
+	}{
+		{"equal", "a: 1\nb: [x]\n", "a: 1\nb: [x]\n", nil},
+		{"changed", "a: 1\n", "a: 2\n", []DiffEntry{{Kind: DiffChanged, Path: "a", Old: 1, New: 2}}},
+		{"added subtree", "a: 1\n", "a: 1\nb:\n  d: 4\n  c: 3\n", []DiffEntry{
+			{Kind: DiffAdded, Path: "b.c", New: 3},
+			{Kind: DiffAdded, Path: "b.d", New: 4},
+		}},
+		{"removed key", "a: 1\nb: 2\n", "a: 1\n", []DiffEntry{{Kind: DiffRemoved, Path: "b", Old: 2}}},
+		{"list items", "a: [x, y]\n", "a: [x, z, w]\n", []DiffEntry{
+			{Kind: DiffChanged, Path: "a[1]", Old: "y", New: "z"},
+			{Kind: DiffAdded, Path: "a[2]", New: "w"},
+		}},
+		{"type change", "a: {b: 1}\n", "a: 1\n", []DiffEntry{{Kind: DiffChanged, Path: "a", Old: map[string]interface{}{"b": 1}, New: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var old, updated interface{}
+			if err := yaml.Unmarshal([]byte(tt.old), &old); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.updated), &updated); err != nil {
+				t.Fatal(err)
+			}
+			if got := diffTrees("", old, updated); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintDiffMasksSecrets(t *testing.T) {
+	entries := []DiffEntry{
+		{Kind: DiffChanged, Path: "backend.database.connection.password", Old: "old-db", New: "new-db"},
+		{Kind: DiffAdded, Path: "integrations.gitlab[0].token", New: "glpat-abc"},
+		{Kind: DiffAdded, Path: "proxy.endpoints./api.headers.X-Custom", New: "letmein"},
+		{Kind: DiffAdded, Path: "custom.provider.apiKey", New: "k-123"},
+		{Kind: DiffAdded, Path: "custom.provider.clientSecret", New: "cs-123"},
+		{Kind: DiffRemoved, Path: "auth.providers.github.development.privateKey", Old: "pem"},
+		{Kind: DiffAdded, Path: "integrations.github[0].token", New: "${GITHUB_TOKEN}"},
+		{Kind: DiffAdded, Path: "app.title", New: "Portal"},
+	}
+	var out bytes.Buffer
+	printDiff(&out, entries, map[string]bool{"backend.database.connection.password": true})
+	want := `~ backend.database.connection.password: ******** -> ********
++ integrations.gitlab[0].token: ********
++ proxy.endpoints./api.headers.X-Custom: ********
++ custom.provider.apiKey: ********
++ custom.provider.clientSecret: ********
+- auth.providers.github.development.privateKey: ********
++ integrations.github[0].token: ${GITHUB_TOKEN}
++ app.title: Portal
+`
+	if out.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestBackupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app-config.yaml")
+	if err := os.WriteFile(path, []byte("app: {}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Backups made in a row, usually within the same second, must not overwrite each other
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		backup, err := backupFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[backup] {
+			t.Fatalf("backup %s was written twice", backup)
+		}
+		seen[backup] = true
+		data, err := os.ReadFile(backup)
+		if err != nil || string(data) != "app: {}\n" {
+			t.Errorf("backup %s holds %q, %v", backup, data, err)
+		}
+		if info, err := os.Stat(backup); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("backup %s does not keep the mode of the original: %v", backup, err)
+		}
+	}
+}
+
+func TestComposeBuildContext(t *testing.T) {
+	var err error
+	if defaults, err = loadDefaults(""); err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "backstage.json"), []byte(`{"version": "1.41.1"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"app root", root, "."},
+		{"below the app root", filepath.Join(root, "deploy", "compose"), "../.."},
+		{"outside an app", t.TempDir(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := composeBuildContext(tt.dir); err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	stack, err := renderComposeStack(&Config{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compose := string(stack.Files[composeFileName]); strings.Contains(compose, "build") {
+		t.Errorf("the stack builds the image without a build context:\n%s", compose)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app-config.yaml")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Replacing a world-readable file with secrets tightens its mode
+	if err := writeFileAtomic(path, []byte("new\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new\n" {
+		t.Errorf("got %q, %v, want the new content", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("got mode %v, %v, want 0600", info.Mode().Perm(), err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("the temporary file was left behind: %v", entries)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, "missing", "app-config.yaml"), []byte("new\n"), 0600); err == nil {
+		t.Error("expected an error writing into a missing directory")
+	}
+}
+
+// gitRepo returns a new git repository holding a tracked and an untracked file
+func gitRepo(t *testing.T) (tracked, untracked string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	tracked, untracked = filepath.Join(dir, "tracked.yaml"), filepath.Join(dir, "untracked.yaml")
+	for _, path := range []string{tracked, untracked} {
+		if err := os.WriteFile(path, []byte("app: {}\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init", "-q"}, {"add", "tracked.yaml"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+	}
+	return tracked, untracked
+}
+
+func TestIsGitTracked(t *testing.T) {
+	tracked, untracked := gitRepo(t)
+	if !isGitTracked(tracked) {
+		t.Errorf("%s is tracked", tracked)
+	}
+	if isGitTracked(untracked) {
+		t.Errorf("%s is not tracked", untracked)
+	}
+	if isGitTracked(filepath.Join(filepath.Dir(tracked), "new.yaml")) {
+		t.Error("a file that does not exist yet is not tracked")
+	}
+}
+
+func TestRefusePlaintextSecrets(t *testing.T) {
+	tracked, untracked := gitRepo(t)
+	secrets := []string{"backend.database.connection.password"}
+	tests := []struct {
+		name    string
+		path    string
+		secrets []string
+		allow   bool
+		refused bool
+	}{
+		{"tracked", tracked, secrets, false, true},
+		{"allowed", tracked, secrets, true, false},
+		{"untracked", untracked, secrets, false, false},
+		{"no secrets", tracked, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := refusePlaintextSecrets(&out, tt.path, tt.secrets, outputOptions{allowPlaintextSecrets: tt.allow}); got != tt.refused {
+				t.Errorf("refused %v, want %v", got, tt.refused)
+			}
+			if tt.refused && !strings.Contains(out.String(), "  backend.database.connection.password\n") {
+				t.Errorf("the secrets are not listed:\n%s", out.String())
+			}
+			if !tt.refused && out.Len() > 0 {
+				t.Errorf("unexpected output:\n%s", out.String())
+			}
+		})
+	}
+}
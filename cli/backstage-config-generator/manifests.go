@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// K8sObject covers the fields of the handful of Kubernetes kinds the generator emits
+type K8sObject struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   K8sMetadata       `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+	Spec       interface{}       `yaml:"spec,omitempty"`
+	Rules      []K8sPolicyRule   `yaml:"rules,omitempty"`
+	RoleRef    *K8sRoleRef       `yaml:"roleRef,omitempty"`
+	Subjects   []K8sSubject      `yaml:"subjects,omitempty"`
+}
+
+type K8sMetadata struct {
+	Name        string            `yaml:"name,omitempty"`
+	Namespace   string            `yaml:"namespace,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+type K8sPolicyRule struct {
+	APIGroups []string `yaml:"apiGroups"`
+	Resources []string `yaml:"resources"`
+	Verbs     []string `yaml:"verbs"`
+}
+
+type K8sRoleRef struct {
+	APIGroup string `yaml:"apiGroup"`
+	Kind     string `yaml:"kind"`
+	Name     string `yaml:"name"`
+}
+
+type K8sSubject struct {
+	Kind      string `yaml:"kind"`
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace"`
+}
+
+type K8sDeploymentSpec struct {
+	Replicas int            `yaml:"replicas"`
+	Selector K8sSelector    `yaml:"selector"`
+	Template K8sPodTemplate `yaml:"template"`
+}
+
+type K8sSelector struct {
+	MatchLabels map[string]string `yaml:"matchLabels"`
+}
+
+type K8sPodTemplate struct {
+	Metadata K8sMetadata `yaml:"metadata"`
+	Spec     K8sPodSpec  `yaml:"spec"`
+}
+
+type K8sPodSpec struct {
+	ServiceAccountName string         `yaml:"serviceAccountName,omitempty"`
+	Containers         []K8sContainer `yaml:"containers"`
+	Volumes            []K8sVolume    `yaml:"volumes"`
+}
+
+type K8sContainer struct {
+	Name         string           `yaml:"name"`
+	Image        string           `yaml:"image"`
+	Args         []string         `yaml:"args"`
+	Ports        []K8sPort        `yaml:"ports"`
+	Env          []K8sEnvVar      `yaml:"env,omitempty"`
+	EnvFrom      []K8sEnvFrom     `yaml:"envFrom,omitempty"`
+	VolumeMounts []K8sVolumeMount `yaml:"volumeMounts"`
+}
+
+type K8sPort struct {
+	Name          string `yaml:"name,omitempty"`
+	ContainerPort int    `yaml:"containerPort,omitempty"`
+	Port          int    `yaml:"port,omitempty"`
+	TargetPort    int    `yaml:"targetPort,omitempty"`
+}
+
+type K8sEnvVar struct {
+	Name      string          `yaml:"name"`
+	ValueFrom K8sEnvVarSource `yaml:"valueFrom"`
+}
+
+type K8sEnvVarSource struct {
+	SecretKeyRef K8sSecretKeyRef `yaml:"secretKeyRef"`
+}
+
+type K8sSecretKeyRef struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type K8sEnvFrom struct {
+	SecretRef K8sNameRef `yaml:"secretRef"`
+}
+
+type K8sNameRef struct {
+	Name string `yaml:"name"`
+}
+
+type K8sVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly"`
+}
+
+type K8sVolume struct {
+	Name      string     `yaml:"name"`
+	ConfigMap K8sNameRef `yaml:"configMap"`
+}
+
+type K8sServiceSpec struct {
+	Type     string            `yaml:"type"`
+	Selector map[string]string `yaml:"selector"`
+	Ports    []K8sPort         `yaml:"ports"`
+}
+
+const (
+	k8sAppName       = "backstage"
+	k8sConfigMapName = "backstage-app-config"
+	k8sSecretName    = "backstage-secrets"
+	k8sTokenName     = "backstage-token"
+	k8sConfigDir     = "/app/config"
+)
+
+// cloneConfig returns a deep copy of config, so emitters can rewrite fields freely
+func cloneConfig(config *Config) (*Config, error) {
+	data, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var clone Config
+	if err := yaml.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// extractSecrets replaces every secret in config with a ${VAR} placeholder and
+// returns the variables with their values. Values that are only known at
+// runtime, like existing placeholders and references, are returned empty.
+func extractSecrets(config *Config) map[string]string {
+	env := make(map[string]string)
+	walkSecrets(config, func(path, value string) string {
+		var name string
+		switch {
+		case isPlaceholder(value):
+			name = strings.TrimSuffix(strings.TrimPrefix(value, "${"), "}")
+			value = ""
+		case strings.HasPrefix(value, "env://"):
+			name = strings.TrimPrefix(value, "env://")
+			value = ""
+		case isSecretRef(value):
+			name = envVarName(path)
+			value = ""
+		default:
+			name = envVarName(path)
+		}
+		if _, exists := env[name]; !exists || value != "" {
+			env[name] = value
+		}
+		return fmt.Sprintf("${%s}", name)
+	})
+	return env
+}
+
+func backendPort(config *Config) int {
+	port, err := strconv.Atoi(config.Backend.Listen.Port)
+	if err != nil {
+		return 7007
+	}
+	return port
+}
+
+// serviceAccountTokenVars returns the variables holding the token of the clusters
+// reached with the ServiceAccount of the RBAC, and removes them from env so they
+// are read from its token Secret instead. Those are the clusters using the
+// in-cluster API server, or the only service account cluster when none does.
+// config has to have its secrets extracted into env.
+func serviceAccountTokenVars(config *Config, env map[string]string) []string {
+	if config.Kubernetes == nil {
+		return nil
+	}
+	var inCluster, others []string
+	for _, method := range config.Kubernetes.ClusterLocatorMethods {
+		for _, cluster := range method.Clusters {
+			name := strings.TrimSuffix(strings.TrimPrefix(cluster.ServiceAccountToken, "${"), "}")
+			// A token given in the config is kept
+			if cluster.AuthProvider != "serviceAccount" || !isPlaceholder(cluster.ServiceAccountToken) || env[name] != "" {
+				continue
+			}
+			if strings.Contains(cluster.Url, "kubernetes.default.svc") {
+				inCluster = append(inCluster, name)
+			} else {
+				others = append(others, name)
+			}
+		}
+	}
+	names := inCluster
+	if len(names) == 0 && len(others) == 1 {
+		names = others
+	}
+	for _, name := range names {
+		delete(env, name)
+	}
+	return names
+}
+
+// needsClusterRBAC reports whether Backstage reads from the Kubernetes API
+func needsClusterRBAC(config *Config) bool {
+	return config.Kubernetes != nil || config.KubernetesIngestor != nil
+}
+
+// renderK8sManifests renders the app-config as a ConfigMap along with the Secret,
+// Deployment, Service and the RBAC described in kubernetes-ingestor/K8S_RBAC.md.
+func renderK8sManifests(config *Config) ([]byte, error) {
+	deployConfig, err := cloneConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	env := extractSecrets(deployConfig)
+	var tokenVars []string
+	if needsClusterRBAC(config) {
+		tokenVars = serviceAccountTokenVars(deployConfig, env)
+	}
+	appConfig, err := marshalConfig(deployConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := defaults.Deploy.Namespace
+	labels := map[string]string{"app.kubernetes.io/name": k8sAppName}
+	meta := func(name string) K8sMetadata {
+		return K8sMetadata{Name: name, Namespace: namespace, Labels: labels}
+	}
+	port := backendPort(config)
+
+	objects := []K8sObject{
+		{APIVersion: "v1", Kind: "Namespace", Metadata: K8sMetadata{Name: namespace}},
+		{APIVersion: "v1", Kind: "ConfigMap", Metadata: meta(k8sConfigMapName), Data: map[string]string{"app-config.yaml": string(appConfig)}},
+	}
+	if len(env) > 0 {
+		objects = append(objects, K8sObject{APIVersion: "v1", Kind: "Secret", Metadata: meta(k8sSecretName), Type: "Opaque", StringData: env})
+	}
+
+	podSpec := K8sPodSpec{
+		Containers: []K8sContainer{{
+			Name:  k8sAppName,
+			Image: defaults.Deploy.Image,
+			// args keep the ENTRYPOINT of the image
+			Args:  []string{"node", "packages/backend", "--config", k8sConfigDir + "/app-config.yaml"},
+			Ports: []K8sPort{{Name: "http", ContainerPort: port}},
+			VolumeMounts: []K8sVolumeMount{
+				{Name: "app-config", MountPath: k8sConfigDir, ReadOnly: true},
+			},
+		}},
+		Volumes: []K8sVolume{{Name: "app-config", ConfigMap: K8sNameRef{Name: k8sConfigMapName}}},
+	}
+	for _, name := range tokenVars {
+		podSpec.Containers[0].Env = append(podSpec.Containers[0].Env, K8sEnvVar{
+			Name:      name,
+			ValueFrom: K8sEnvVarSource{SecretKeyRef: K8sSecretKeyRef{Name: k8sTokenName, Key: "token"}},
+		})
+	}
+	if len(env) > 0 {
+		podSpec.Containers[0].EnvFrom = []K8sEnvFrom{{SecretRef: K8sNameRef{Name: k8sSecretName}}}
+	}
+	if needsClusterRBAC(config) {
+		podSpec.ServiceAccountName = defaults.Deploy.ServiceAccount
+	}
+
+	objects = append(objects,
+		K8sObject{APIVersion: "apps/v1", Kind: "Deployment", Metadata: meta(k8sAppName), Spec: K8sDeploymentSpec{
+			Replicas: 1,
+			Selector: K8sSelector{MatchLabels: labels},
+			Template: K8sPodTemplate{Metadata: K8sMetadata{Labels: labels}, Spec: podSpec},
+		}},
+		K8sObject{APIVersion: "v1", Kind: "Service", Metadata: meta(k8sAppName), Spec: K8sServiceSpec{
+			Type:     "ClusterIP",
+			Selector: labels,
+			Ports:    []K8sPort{{Name: "http", Port: port, TargetPort: port}},
+		}},
+	)
+	if needsClusterRBAC(config) {
+		objects = append(objects, ingestorServiceAccount(namespace))
+		objects = append(objects, ingestorRBAC(config, namespace)...)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	for _, object := range objects {
+		if err := encoder.Encode(object); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	warnEmptySecrets(env)
+	return buf.Bytes(), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// warnEmptySecrets lists the secrets whose values are only known at deploy time
+func warnEmptySecrets(env map[string]string) {
+	var missing []string
+	for _, name := range sortedKeys(env) {
+		if env[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Fill in the values of %s in Secret %s before deploying\n", strings.Join(missing, ", "), k8sSecretName)
+	}
+}
+
+// ingestorServiceAccount returns the ServiceAccount the RBAC of ingestorRBAC is bound to
+func ingestorServiceAccount(namespace string) K8sObject {
+	return K8sObject{APIVersion: "v1", Kind: "ServiceAccount", Metadata: K8sMetadata{Name: defaults.Deploy.ServiceAccount, Namespace: namespace}}
+}
+
+// ingestorRBAC returns the long lived token of the ingestorServiceAccount and the
+// cluster roles needed for the features that are enabled in config
+func ingestorRBAC(config *Config, namespace string) []K8sObject {
+	serviceAccount := defaults.Deploy.ServiceAccount
+	subjects := []K8sSubject{{Kind: "ServiceAccount", Name: serviceAccount, Namespace: namespace}}
+	binding := func(name, role string) K8sObject {
+		return K8sObject{
+			APIVersion: "rbac.authorization.k8s.io/v1",
+			Kind:       "ClusterRoleBinding",
+			Metadata:   K8sMetadata{Name: name},
+			RoleRef:    &K8sRoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: role},
+			Subjects:   subjects,
+		}
+	}
+
+	objects := []K8sObject{
+		{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata: K8sMetadata{
+				Name:        k8sTokenName,
+				Namespace:   namespace,
+				Annotations: map[string]string{"kubernetes.io/service-account.name": serviceAccount},
+			},
+			Type: "kubernetes.io/service-account-token",
+		},
+		binding("backstage-kubernetes-ingestor-rbac", "view"),
+	}
+
+	crossplaneIngestion := config.KubernetesIngestor != nil &&
+		(config.KubernetesIngestor.Crossplane.Claims.IngestAllClaims || config.KubernetesIngestor.Crossplane.Xrds.Enabled)
+	if crossplaneIngestion || (config.KubernetesIngestor != nil && config.KubernetesIngestor.GenericCRDTemplates != nil) {
+		objects = append(objects,
+			K8sObject{
+				APIVersion: "rbac.authorization.k8s.io/v1",
+				Kind:       "ClusterRole",
+				Metadata:   K8sMetadata{Name: "backstage-crd-viewer"},
+				Rules: []K8sPolicyRule{{
+					APIGroups: []string{"apiextensions.k8s.io"},
+					Resources: []string{"customresourcedefinitions"},
+					Verbs:     []string{"get", "list", "watch"},
+				}},
+			},
+			binding("backstage-crossplane-ingestion-crd-rbac", "backstage-crd-viewer"),
+		)
+	}
+	if config.Crossplane != nil {
+		// Visualization also reads managed resources, crossplane-view covers them
+		objects = append(objects, binding("backstage-crossplane-visualization-rbac", "crossplane-view"))
+	} else if crossplaneIngestion {
+		// The basic setup of K8S_RBAC.md binds crossplane-view for ingestion too
+		objects = append(objects, binding("backstage-crossplane-ingestion-rbac", "crossplane-view"))
+	}
+	return objects
+}
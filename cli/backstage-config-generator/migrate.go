@@ -0,0 +1,338 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type MigrationKind int
+
+const (
+	// MigrateMove moves the value at From to To, renames are moves within the same parent
+	MigrateMove MigrationKind = iota
+	// MigrateCopy copies the value at From to To when To is not set but its parent is
+	MigrateCopy
+)
+
+// MigrationStep is a single change to the YAML tree, keys are dotted paths
+type MigrationStep struct {
+	Kind MigrationKind
+	From string
+	To   string
+}
+
+// Migration groups the config changes of a kubernetes-ingestor release,
+// Source cites the file and release the change can be checked in
+type Migration struct {
+	Version     string
+	Description string
+	Source      string
+	Steps       []MigrationStep
+}
+
+// migrations is the ordered registry of config changes, oldest first. Only
+// changes that can be checked in a plugin release belong here.
+var migrations = []Migration{
+	{
+		Version:     "1.18.1",
+		Description: "kubernetes-ingestor reads its Crossplane settings from kubernetesIngestor.crossplane",
+		Source:      "provider/KubernetesDataProvider.ts and provider/XrdDataProvider.ts, kubernetes-ingestor 1.18.1",
+		Steps: []MigrationStep{
+			{Kind: MigrateMove, From: "kubernetesIngestor.claims", To: "kubernetesIngestor.crossplane.claims"},
+			{Kind: MigrateMove, From: "kubernetesIngestor.xrds", To: "kubernetesIngestor.crossplane.xrds"},
+		},
+	},
+	{
+		Version:     "1.18.1",
+		Description: "kubernetes-ingestor publishes generic CRD templates with kubernetesIngestor.genericCRDTemplates.publishPhase",
+		Source:      "provider/EntityProvider.ts, kubernetes-ingestor 1.18.1",
+		Steps: []MigrationStep{
+			{Kind: MigrateCopy, From: "kubernetesIngestor.crossplane.xrds.publishPhase", To: "kubernetesIngestor.genericCRDTemplates.publishPhase"},
+		},
+	},
+}
+
+// ingestorReleases maps Backstage releases to the kubernetes-ingestor version
+// released with them, oldest first
+var ingestorReleases = []struct{ Backstage, Ingestor string }{
+	// backstage.json and plugins/kubernetes-ingestor/package.json of this repository
+	{"1.41.1", "1.18.1"},
+}
+
+// parseVersion reads a major.minor.patch version, missing parts are zero
+func parseVersion(version string) ([3]int, error) {
+	var parsed [3]int
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	if len(parts) > 3 {
+		return parsed, fmt.Errorf("invalid version %q", version)
+	}
+	for i, part := range parts {
+		// Drop pre-release suffixes such as 1.42.0-next.1
+		part, _, _ = strings.Cut(part, "-")
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid version %q", version)
+		}
+		parsed[i] = n
+	}
+	return parsed, nil
+}
+
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// backstageVersion reads the Backstage version of the app from its backstage.json
+func backstageVersion(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var manifest struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if manifest.Version == "" {
+		return "", fmt.Errorf("%s has no version", path)
+	}
+	return manifest.Version, nil
+}
+
+// ingestorVersion returns the kubernetes-ingestor version of the latest known
+// release up to a Backstage version, the zero version when it predates them all
+func ingestorVersion(backstage [3]int) ([3]int, error) {
+	var version [3]int
+	for _, release := range ingestorReleases {
+		releaseVersion, err := parseVersion(release.Backstage)
+		if err != nil {
+			return version, err
+		}
+		if compareVersions(releaseVersion, backstage) > 0 {
+			break
+		}
+		if version, err = parseVersion(release.Ingestor); err != nil {
+			return version, err
+		}
+	}
+	return version, nil
+}
+
+// pendingMigrations returns the migrations of the kubernetes-ingestor releases
+// after the one of Backstage from, up to and including the one of Backstage to
+func pendingMigrations(from, to string) ([]Migration, error) {
+	fromVersion, err := parseVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := parseVersion(to)
+	if err != nil {
+		return nil, err
+	}
+	if compareVersions(fromVersion, toVersion) > 0 {
+		return nil, fmt.Errorf("cannot migrate back from %s to %s", from, to)
+	}
+	if fromVersion, err = ingestorVersion(fromVersion); err != nil {
+		return nil, err
+	}
+	if toVersion, err = ingestorVersion(toVersion); err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, migration := range migrations {
+		version, err := parseVersion(migration.Version)
+		if err != nil {
+			return nil, err
+		}
+		if compareVersions(version, fromVersion) > 0 && compareVersions(version, toVersion) <= 0 {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// lookupNode returns the value node at path in a mapping, and the mapping holding it
+func lookupNode(root *yaml.Node, path string) (value, parent *yaml.Node) {
+	node := root
+	for _, segment := range strings.Split(path, ".") {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		parent = node
+		node = nil
+		for i := 0; i+1 < len(parent.Content); i += 2 {
+			if parent.Content[i].Value == segment {
+				node = parent.Content[i+1]
+				break
+			}
+		}
+	}
+	return node, parent
+}
+
+// removeNode removes the key at path and returns its key and value nodes
+func removeNode(root *yaml.Node, path string) (key, value *yaml.Node) {
+	_, parent := lookupNode(root, path)
+	if parent == nil {
+		return nil, nil
+	}
+	name := path[strings.LastIndex(path, ".")+1:]
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == name {
+			key, value = parent.Content[i], parent.Content[i+1]
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return key, value
+		}
+	}
+	return nil, nil
+}
+
+// setNode adds the key at path, creating the mappings leading to it. It fails
+// if one of them is set to something else than a mapping.
+func setNode(root *yaml.Node, path string, key, value *yaml.Node) error {
+	segments := strings.Split(path, ".")
+	node := root
+	for i, segment := range segments[:len(segments)-1] {
+		child, _ := lookupNode(node, segment)
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: segment}, child)
+		}
+		// An explicit null such as "crossplane:" becomes the mapping
+		if child.Kind == yaml.ScalarNode && child.Tag == "!!null" {
+			child.Kind, child.Tag, child.Value = yaml.MappingNode, "!!map", ""
+		}
+		if child.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a mapping", strings.Join(segments[:i+1], "."))
+		}
+		node = child
+	}
+	if key == nil {
+		key = &yaml.Node{Kind: yaml.ScalarNode, Value: segments[len(segments)-1]}
+	}
+	key.Value = segments[len(segments)-1]
+	if len(node.Content) == 0 {
+		// Write what was an empty {} as a block mapping
+		node.Style &^= yaml.FlowStyle
+	}
+	node.Content = append(node.Content, key, value)
+	return nil
+}
+
+// applyStep applies a migration step to the root mapping and describes what it
+// did, an empty description means there was nothing to migrate
+func applyStep(root *yaml.Node, step MigrationStep) (string, error) {
+	existing, _ := lookupNode(root, step.To)
+	switch step.Kind {
+	case MigrateMove:
+		value, _ := lookupNode(root, step.From)
+		if value == nil {
+			return "", nil
+		}
+		if existing != nil {
+			return "", fmt.Errorf("both %s and %s are set, merge them by hand", step.From, step.To)
+		}
+		key, value := removeNode(root, step.From)
+		if err := setNode(root, step.To, key, value); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("moved %s to %s", step.From, step.To), nil
+	case MigrateCopy:
+		value, _ := lookupNode(root, step.From)
+		if value == nil || existing != nil {
+			return "", nil
+		}
+		if parent := step.To[:strings.LastIndex(step.To, ".")]; !hasNode(root, parent) {
+			return "", nil
+		}
+		if err := setNode(root, step.To, nil, copyNode(value)); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("copied %s to %s", step.From, step.To), nil
+	}
+	return "", fmt.Errorf("unknown migration step %d", step.Kind)
+}
+
+func hasNode(root *yaml.Node, path string) bool {
+	node, _ := lookupNode(root, path)
+	return node != nil
+}
+
+// copyNode deep copies a node so the copy can be changed on its own
+func copyNode(node *yaml.Node) *yaml.Node {
+	copied := *node
+	copied.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		copied.Content[i] = copyNode(child)
+	}
+	return &copied
+}
+
+// migrateConfig applies the migrations to a config, keeping its comments and
+// key order, and returns the migrated YAML with the report of the changes
+func migrateConfig(data []byte, pending []Migration) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("the config is not a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	var report []string
+	for _, migration := range pending {
+		header := fmt.Sprintf("%s: %s", migration.Version, migration.Description)
+		for _, step := range migration.Steps {
+			change, err := applyStep(root, step)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", migration.Version, err)
+			}
+			if change == "" {
+				continue
+			}
+			if header != "" {
+				report = append(report, header, "  source: "+migration.Source)
+				header = ""
+			}
+			report = append(report, "  "+change)
+		}
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, err
+	}
+	encoder.Close()
+	return out.Bytes(), report, nil
+}
+
+// printMigrationReport lists the changes made by the migrations
+func printMigrationReport(w io.Writer, from, to string, pending []Migration, report []string) {
+	fmt.Fprintf(w, "Migrating from Backstage %s to %s, %d migration(s) apply\n", from, to, len(pending))
+	if len(report) == 0 {
+		fmt.Fprintln(w, "No changes needed")
+		return
+	}
+	for _, line := range report {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+}
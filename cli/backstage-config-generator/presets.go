@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Preset pre-selects the sections to configure. While a preset is active, the
+// yes/no questions, choices and lists named in Defaults take their default and
+// every other question is asked.
+type Preset struct {
+	Name        string
+	Description string
+	Sections    []string
+	// Defaults are section.question IDs whose default the preset accepts
+	Defaults []string
+}
+
+// presetDefaults are the settings every preset takes the defaults of. Choices
+// that depend on the environment, such as the database, the TechDocs storage,
+// the publish target and how clusters are found and authenticated, are asked.
+var presetDefaults = []string{
+	"techdocs.builder", "techdocs.runIn", "techdocs.s3ForcePathStyle", "techdocs.cache",
+	"kubernetes.type", "kubernetes.skipTLSVerify", "kubernetes.dashboard", "kubernetes.dashboardApp",
+	"kubernetes.skipMetricsLookup", "kubernetes.exposeDashboard",
+	"kubernetesIngestor.namespaceModel", "kubernetesIngestor.nameModel", "kubernetesIngestor.titleModel",
+	"kubernetesIngestor.systemModel", "kubernetesIngestor.referencesNamespaceModel", "kubernetesIngestor.components",
+	"kubernetesIngestor.excludedNamespaces", "kubernetesIngestor.disableDefaultWorkloadTypes",
+	"kubernetesIngestor.onlyIngestAnnotatedResources", "kubernetesIngestor.addCustomWorkloadTypes",
+	"kubernetesIngestor.ingestAllClaims", "kubernetesIngestor.convertDefaultValuesToPlaceholders",
+	"kubernetesIngestor.xrds", "kubernetesIngestor.ingestAllXRDs", "kubernetesIngestor.allowRepoSelection",
+	"kubernetesIngestor.allowedTargets",
+	"scaleops.linkToDashboard", "scaleops.authentication",
+	"proxy.preset", "proxy.changeOrigin", "proxy.credentials", "proxy.secure", "proxy.addAuthorization",
+	"proxy.allowedMethods", "proxy.allowedHeaders", "proxy.rewrite",
+	"permission.policyFileReload", "permission.pluginsWithPermission",
+	"crossplane.enablePermissions", "kyverno.enablePermissions",
+	"vcfAutomation.enablePermissions", "vcfAutomation.majorVersion",
+	"scaffolder.setDefaultAuthor", "scaffolder.teraskyUtils",
+}
+
+var presets = map[string]Preset{
+	"minimal": {
+		Name:        "minimal",
+		Description: "Core Backstage settings only, no integrations or plugins",
+		Defaults:    presetDefaults,
+	},
+	"demo": {
+		Name:        "demo",
+		Description: "GitHub integration and authentication with Kubernetes, the ingestor and the visualization plugins",
+		Sections:    []string{"github", "githubAuth", "kubernetes", "kubernetesIngestor", "crossplane", "kyverno", "devpod"},
+		Defaults:    presetDefaults,
+	},
+	"crossplane-platform": {
+		Name:        "crossplane-platform",
+		Description: "Kubernetes, Kubernetes Ingestor, Crossplane and Kyverno with scaffolder actions and RBAC",
+		Sections:    []string{"github", "kubernetes", "kubernetesIngestor", "crossplane", "kyverno", "scaffolder", "permission"},
+		Defaults:    presetDefaults,
+	},
+	"vcf-platform": {
+		Name:        "vcf-platform",
+		Description: "VCF Automation with the Kubernetes Ingestor for VMware platforms, with scaffolder actions and RBAC",
+		Sections:    []string{"github", "vcfAutomation", "kubernetes", "kubernetesIngestor", "scaffolder", "permission"},
+		Defaults:    presetDefaults,
+	},
+	"full": {
+		Name:        "full",
+		Description: "Every section the generator supports",
+		Sections: []string{
+			"github", "microsoftAuth", "githubAuth", "microsoftGraph", "kubernetes", "kubernetesIngestor",
+			"vcfAutomation", "scaleops", "proxy", "devpod", "permission", "crossplane", "kyverno", "scaffolder",
+		},
+		Defaults: presetDefaults,
+	},
+}
+
+// activePreset is set from --preset before any prompt runs
+var activePreset *Preset
+
+func (p *Preset) enables(section string) bool {
+	return contains(p.Sections, section)
+}
+
+// acceptsDefault tells whether the preset answers question id of section with its default
+func (p *Preset) acceptsDefault(section, id string) bool {
+	return contains(p.Defaults, section+"."+id)
+}
+
+func lookupPreset(name string) (*Preset, error) {
+	preset, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown preset %q, run 'presets list' to see the available presets", name)
+	}
+	return &preset, nil
+}
+
+func listPresets(w io.Writer) {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		preset := presets[name]
+		fmt.Fprintf(w, "%s\n  %s\n", preset.Name, preset.Description)
+		if len(preset.Sections) > 0 {
+			fmt.Fprintf(w, "  sections: %v\n", preset.Sections)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed defaults.yaml
+var embeddedDefaults []byte
+
+// Defaults holds the default answers offered by the prompts. The embedded
+// defaults.yaml is always loaded first, a team profile only needs the keys it changes.
+type Defaults struct {
+	App                AppConfig                  `yaml:"app"`
+	Organization       OrgConfig                  `yaml:"organization"`
+	Backend            BackendDefaults            `yaml:"backend"`
+	Techdocs           TechdocsDefaults           `yaml:"techdocs"`
+	Kubernetes         KubernetesDefaults         `yaml:"kubernetes"`
+	KubernetesIngestor KubernetesIngestorDefaults `yaml:"kubernetesIngestor"`
+	PublishPhase       PublishPhaseDefaults       `yaml:"publishPhase"`
+	Scaleops           ScaleopsDefaults           `yaml:"scaleops"`
+	Permission         PermissionDefaults         `yaml:"permission"`
+	Devpod             DevpodConfig               `yaml:"devpod"`
+	Deploy             DeployDefaults             `yaml:"deploy"`
+}
+
+type BackendDefaults struct {
+	Port     string `yaml:"port"`
+	Database string `yaml:"database"`
+}
+
+type TechdocsDefaults struct {
+	Builder   string `yaml:"builder"`
+	RunIn     string `yaml:"runIn"`
+	Publisher string `yaml:"publisher"`
+	S3Region  string `yaml:"s3Region"`
+}
+
+type KubernetesDefaults struct {
+	AuthProvider      string `yaml:"authProvider"`
+	OidcTokenProvider string `yaml:"oidcTokenProvider"`
+}
+
+type KubernetesIngestorDefaults struct {
+	Mappings           MappingsConfig `yaml:"mappings"`
+	ExcludedNamespaces []string       `yaml:"excludedNamespaces"`
+}
+
+type PublishPhaseDefaults struct {
+	Target       string `yaml:"target"`
+	Host         string `yaml:"host"`
+	Owner        string `yaml:"owner"`
+	Repo         string `yaml:"repo"`
+	TargetBranch string `yaml:"targetBranch"`
+}
+
+type ScaleopsDefaults struct {
+	BaseUrl        string `yaml:"baseUrl"`
+	CurrencyPrefix string `yaml:"currencyPrefix"`
+}
+
+type PermissionDefaults struct {
+	PoliciesCSVFile       string   `yaml:"policiesCSVFile"`
+	PluginsWithPermission []string `yaml:"pluginsWithPermission"`
+}
+
+// DeployDefaults are used when emitting deployment manifests instead of an app-config
+type DeployDefaults struct {
+	Image          string `yaml:"image"`
+	Namespace      string `yaml:"namespace"`
+	ServiceAccount string `yaml:"serviceAccount"`
+	BuildContext   string `yaml:"buildContext"`
+	Dockerfile     string `yaml:"dockerfile"`
+}
+
+// defaults is loaded in main before any prompt runs
+var defaults Defaults
+
+// loadDefaults reads the embedded profile and applies the optional team profile on top of it
+func loadDefaults(path string) (Defaults, error) {
+	var d Defaults
+	if err := decodeDefaults(embeddedDefaults, &d); err != nil {
+		return d, fmt.Errorf("parsing embedded defaults: %w", err)
+	}
+	if path == "" {
+		return d, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return d, err
+	}
+	if err := decodeDefaults(data, &d); err != nil {
+		return d, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// decodeDefaults rejects unknown keys so typos in a team profile are not silently ignored
+func decodeDefaults(data []byte, d *Defaults) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(d); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
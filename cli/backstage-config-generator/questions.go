@@ -0,0 +1,588 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type QuestionType int
+
+const (
+	QuestionString QuestionType = iota
+	QuestionBool
+	QuestionChoice
+	QuestionList
+	QuestionMultiSelect
+	// QuestionGroup asks its questions in the same scope when DependsOn holds
+	QuestionGroup
+	// QuestionRepeat asks its questions once per item, for as long as the user adds items
+	QuestionRepeat
+)
+
+// Question declares a single prompt of a section. The engine asks it through the
+// active frontend, or takes the answer from the answers file or the preset.
+type Question struct {
+	ID   string
+	Type QuestionType
+	// Key is the app-config key the answer ends up in, used by explain and "?"
+	Key    string
+	Prompt string
+	// Help is the fallback for keys help.yaml has no description of, the
+	// description extracted from the docs is shown when there is one
+	Help string
+	// Heading is printed before the question, to introduce a group of questions
+	Heading string
+
+	Default     string
+	DefaultFrom func(a *Answers) string
+	Options     []string
+	OptionsFrom func(a *Answers) []string
+	Validate    func(value string, a *Answers) error
+	DependsOn   func(a *Answers) bool
+	// Required rejects an empty answer, for values without a usable default
+	Required bool
+
+	// Preset names the preset section a yes/no question enables. When a preset is
+	// active the preset answers the question instead of the user.
+	Preset string
+
+	// Questions are the questions of a group, or of each item of a repeat
+	Questions []Question
+	// More is the default answer of the "add another" prompt of a repeat with n items
+	More func(a *Answers, n int) bool
+	// Then runs after each item of a repeat, to show the user the effect of their answers
+	Then func(item *Answers)
+}
+
+func (q *Question) defaultValue(a *Answers) string {
+	if q.DefaultFrom != nil {
+		return q.DefaultFrom(a)
+	}
+	return q.Default
+}
+
+func (q *Question) options(a *Answers) []string {
+	if q.OptionsFrom != nil {
+		return q.OptionsFrom(a)
+	}
+	return q.Options
+}
+
+// normalize checks an answer and converts it to its canonical form: "true" or
+// "false" for yes/no questions and comma-separated values for lists
+func (q *Question) normalize(value string, options []string, a *Answers) (string, error) {
+	value = strings.TrimSpace(value)
+	switch q.Type {
+	case QuestionBool:
+		switch strings.ToLower(value) {
+		case "y", "yes", "true":
+			value = "true"
+		default:
+			value = "false"
+		}
+	case QuestionChoice:
+		if !contains(options, value) {
+			return "", fmt.Errorf("invalid choice %s, expected one of %s", value, strings.Join(options, ", "))
+		}
+	case QuestionList, QuestionMultiSelect:
+		value = strings.Join(splitList(value), ",")
+		if q.Type == QuestionMultiSelect {
+			for _, item := range splitList(value) {
+				if !contains(options, item) {
+					return "", fmt.Errorf("invalid selection %s", item)
+				}
+			}
+		}
+	}
+	if q.Required && value == "" {
+		return "", fmt.Errorf("an answer is required")
+	}
+	if q.Validate != nil && value != "" {
+		if err := q.Validate(value, a); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func boolString(b bool) string {
+	return strconv.FormatBool(b)
+}
+
+// when is the DependsOn condition of questions that follow a yes/no question
+func when(id string) func(a *Answers) bool {
+	return func(a *Answers) bool { return a.Bool(id) }
+}
+
+// whenEquals is the DependsOn condition of questions that follow a choice
+func whenEquals(id string, values ...string) func(a *Answers) bool {
+	return func(a *Answers) bool { return contains(values, a.Get(id)) }
+}
+
+func validatePort(value string, a *Answers) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("%s is not a valid port", value)
+	}
+	return nil
+}
+
+func validateNumber(value string, a *Answers) error {
+	if _, err := strconv.Atoi(value); err != nil {
+		return fmt.Errorf("%s is not a number", value)
+	}
+	return nil
+}
+
+func validateURL(value string, a *Answers) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s is not an http or https URL", value)
+	}
+	return nil
+}
+
+// Answers holds the answers of a section, or of one item of a repeat. Lookups
+// fall back to the enclosing scope, so item questions can read section answers.
+type Answers struct {
+	parent *Answers
+	values map[string]string
+	items  map[string][]*Answers
+}
+
+func newAnswers(parent *Answers) *Answers {
+	return &Answers{parent: parent, values: make(map[string]string), items: make(map[string][]*Answers)}
+}
+
+func (a *Answers) lookup(id string) (string, bool) {
+	for scope := a; scope != nil; scope = scope.parent {
+		if value, ok := scope.values[id]; ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+func (a *Answers) Get(id string) string {
+	value, _ := a.lookup(id)
+	return value
+}
+
+func (a *Answers) Bool(id string) bool {
+	return a.Get(id) == "true"
+}
+
+func (a *Answers) Int(id string) int {
+	n, _ := strconv.Atoi(a.Get(id))
+	return n
+}
+
+func (a *Answers) List(id string) []string {
+	return splitList(a.Get(id))
+}
+
+// Items returns the items added to a repeat so far
+func (a *Answers) Items(id string) []*Answers {
+	for scope := a; scope != nil; scope = scope.parent {
+		if items, ok := scope.items[id]; ok {
+			return items
+		}
+	}
+	return nil
+}
+
+// Section returns the answers of another section, which are empty if it did not run
+func (a *Answers) Section(id string) *Answers {
+	root := a
+	for root.parent != nil {
+		root = root.parent
+	}
+	if sections := root.items[id]; len(sections) > 0 {
+		return sections[0]
+	}
+	return newAnswers(nil)
+}
+
+// sectionID returns the ID of the section the answers belong to, "" outside of a section
+func (a *Answers) sectionID() string {
+	scope := a
+	for scope.parent != nil && scope.parent.parent != nil {
+		scope = scope.parent
+	}
+	if scope.parent == nil {
+		return ""
+	}
+	for id, sections := range scope.parent.items {
+		if len(sections) > 0 && sections[0] == scope {
+			return id
+		}
+	}
+	return ""
+}
+
+// answers holds the answers of every section that ran, for questions and
+// builders that depend on other sections
+var answers = newAnswers(nil)
+
+// providedAnswers is loaded from --answers. Questions it does not answer are
+// asked through the frontend.
+var providedAnswers *Answers
+
+// loadAnswers reads an answers file keyed by section and question ID. Lists of
+// mappings answer repeats, other lists answer list questions.
+func loadAnswers(path string) (*Answers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	root := newAnswers(nil)
+	for id, value := range tree {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("section %s in %s must be a mapping", id, path)
+		}
+		sectionAnswers, err := answersFromMap(section, root)
+		if err != nil {
+			return nil, fmt.Errorf("section %s: %w", id, err)
+		}
+		root.items[id] = []*Answers{sectionAnswers}
+	}
+	return root, nil
+}
+
+func answersFromMap(m map[string]interface{}, parent *Answers) (*Answers, error) {
+	a := newAnswers(parent)
+	for id, value := range m {
+		switch v := value.(type) {
+		case []interface{}:
+			var scalars []string
+			for _, item := range v {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					itemAnswers, err := answersFromMap(itemMap, a)
+					if err != nil {
+						return nil, err
+					}
+					a.items[id] = append(a.items[id], itemAnswers)
+				} else {
+					scalars = append(scalars, fmt.Sprint(item))
+				}
+			}
+			if len(scalars) > 0 {
+				a.values[id] = strings.Join(scalars, ",")
+			}
+			if len(v) == 0 {
+				a.items[id] = []*Answers{}
+				a.values[id] = ""
+			}
+		case map[string]interface{}:
+			return nil, fmt.Errorf("%s must be a value or a list", id)
+		case nil:
+			a.values[id] = ""
+		default:
+			a.values[id] = fmt.Sprint(v)
+		}
+	}
+	return a, nil
+}
+
+// Frontend collects the raw answer to a question. The engine applies defaults,
+// presets, dependencies and validation, so frontends only handle the input.
+type Frontend interface {
+	Ask(q *Question, options []string, def string) string
+}
+
+type plainFrontend struct{}
+
+func (plainFrontend) Ask(q *Question, options []string, def string) string {
+	switch q.Type {
+	case QuestionBool:
+		defaultStr := "n"
+		if def == "true" {
+			defaultStr = "y"
+		}
+		fmt.Printf("%s (y/n) [%s]: ", q.Prompt, defaultStr)
+	case QuestionChoice:
+		fmt.Printf("%s (%s) [%s]: ", q.Prompt, strings.Join(options, "/"), def)
+	case QuestionList:
+		if def != "" {
+			fmt.Printf("%s [%s]: ", q.Prompt, def)
+		} else {
+			fmt.Printf("%s (comma-separated): ", q.Prompt)
+		}
+	case QuestionMultiSelect:
+		var selected []string
+		for _, i := range promptMultiSelect(q.Prompt, options) {
+			selected = append(selected, options[i])
+		}
+		return strings.Join(selected, ",")
+	default:
+		if def != "" {
+			fmt.Printf("%s [%s]: ", q.Prompt, def)
+		} else {
+			fmt.Printf("%s: ", q.Prompt)
+		}
+	}
+	input := readLine()
+	if input == "" {
+		return def
+	}
+	return input
+}
+
+// tuiFrontend selects yes/no answers and choices with the arrow keys
+type tuiFrontend struct{}
+
+func (tuiFrontend) Ask(q *Question, options []string, def string) string {
+	switch q.Type {
+	case QuestionBool:
+		defaultIndex := 1
+		if def == "true" {
+			defaultIndex = 0
+		}
+		return boolString(selectOptionWithHelp(q.Prompt, []string{"Yes", "No"}, defaultIndex, questionHelp(q, def)) == 0)
+	case QuestionChoice:
+		defaultIndex := 0
+		for i, option := range options {
+			if option == def {
+				defaultIndex = i
+			}
+		}
+		return options[selectOptionWithHelp(q.Prompt, options, defaultIndex, questionHelp(q, def))]
+	}
+	return plainFrontend{}.Ask(q, options, def)
+}
+
+// defaultsFrontend takes the default of every question. Questions that require
+// an answer and have no default, or whose default is rejected, are still asked.
+type defaultsFrontend struct {
+	last *Question
+}
+
+func (f *defaultsFrontend) Ask(q *Question, options []string, def string) string {
+	if (q.Required && def == "") || q == f.last {
+		return activeFrontend().Ask(q, options, def)
+	}
+	f.last = q
+	return def
+}
+
+// hasDefaults reports whether every text and list question of a repeat item
+// has a default. Questions behind DependsOn and nested repeats are left out,
+// they may never be asked.
+func hasDefaults(questions []Question) bool {
+	for i := range questions {
+		q := &questions[i]
+		if q.DependsOn != nil {
+			continue
+		}
+		switch q.Type {
+		case QuestionString, QuestionList:
+			if q.Default == "" && q.DefaultFrom == nil {
+				return false
+			}
+		case QuestionGroup:
+			if !hasDefaults(q.Questions) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func activeFrontend() Frontend {
+	if tuiEnabled {
+		return tuiFrontend{}
+	}
+	return plainFrontend{}
+}
+
+func printHeading(heading string) {
+	fmt.Println("")
+	fmt.Println(heading)
+	fmt.Println(strings.Repeat("=", len(heading)))
+}
+
+// Section is one step of the wizard, declared as questions and a Build function
+// turning the answers into config. Sections run again from scratch when redone.
+type Section struct {
+	ID        string
+	Title     string
+	Questions []Question
+	Build     func(a *Answers, config *Config)
+}
+
+func (s Section) Run(config *Config) error {
+	return s.RunWith(activeFrontend(), config)
+}
+
+// RunWith runs the section collecting the answers with f, the config is left
+// unchanged when an answer from the answers file is invalid
+func (s Section) RunWith(f Frontend, config *Config) error {
+	sectionAnswers := newAnswers(answers)
+	answers.items[s.ID] = []*Answers{sectionAnswers}
+
+	var provided *Answers
+	if providedAnswers != nil {
+		if items := providedAnswers.items[s.ID]; len(items) > 0 {
+			provided = items[0]
+		}
+	}
+
+	printHeading(s.Title + " Configurations")
+	if err := askQuestions(f, s.Questions, sectionAnswers, provided); err != nil {
+		return err
+	}
+	s.Build(sectionAnswers, config)
+	return nil
+}
+
+func askQuestions(f Frontend, questions []Question, a, provided *Answers) error {
+	for i := range questions {
+		q := &questions[i]
+		if q.DependsOn != nil && !q.DependsOn(a) {
+			continue
+		}
+		if q.Heading != "" {
+			printHeading(q.Heading)
+		}
+		var err error
+		switch q.Type {
+		case QuestionGroup:
+			err = askQuestions(f, q.Questions, a, provided)
+		case QuestionRepeat:
+			err = askRepeat(f, q, a, provided)
+		default:
+			a.values[q.ID], err = askQuestion(f, q, a, provided)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// askQuestion returns the answer to q, an error means the answers file has an
+// invalid answer to it
+func askQuestion(f Frontend, q *Question, a, provided *Answers) (string, error) {
+	def := q.defaultValue(a)
+	options := q.options(a)
+	if q.Type == QuestionMultiSelect {
+		if len(options) == 0 {
+			return "", nil
+		}
+		def = strings.Join(options, ",")
+	}
+
+	if provided != nil {
+		if value, ok := provided.values[q.ID]; ok {
+			normalized, err := q.normalize(value, options, a)
+			if err != nil {
+				return "", fmt.Errorf("answers file at %s.%s: %v", a.sectionID(), q.ID, err)
+			}
+			return normalized, nil
+		}
+	}
+
+	if activePreset != nil {
+		if q.Preset != "" {
+			enabled := activePreset.enables(q.Preset)
+			if enabled {
+				fmt.Printf("%s y (preset %s)\n", q.Prompt, activePreset.Name)
+			}
+			return boolString(enabled), nil
+		}
+		if activePreset.acceptsDefault(a.sectionID(), q.ID) {
+			return def, nil
+		}
+	}
+
+	for {
+		input := f.Ask(q, options, def)
+		if input == "?" {
+			fmt.Print(questionHelp(q, def))
+			continue
+		}
+		value, err := q.normalize(input, options, a)
+		if err == nil {
+			return value, nil
+		}
+		fmt.Printf("Invalid answer: %v\n", err)
+	}
+}
+
+// askRepeat asks the item questions once per item. The "add another" prompt is
+// asked even when a preset is active, it would never end if the default was taken.
+func askRepeat(f Frontend, q *Question, a, provided *Answers) error {
+	var providedItems []*Answers
+	fromFile := false
+	if provided != nil {
+		providedItems, fromFile = provided.items[q.ID]
+	}
+
+	if _, ok := f.(*defaultsFrontend); ok && !fromFile && !hasDefaults(q.Questions) {
+		// Items made of defaults alone would be empty, ask for them instead
+		f = activeFrontend()
+	}
+
+	a.items[q.ID] = nil
+	for n := 0; ; n++ {
+		var itemProvided *Answers
+		if fromFile {
+			if n == len(providedItems) {
+				return nil
+			}
+			itemProvided = providedItems[n]
+		} else {
+			if _, ok := f.(*defaultsFrontend); ok && n > 0 {
+				// More may never turn false, defaults add a single item
+				return nil
+			}
+			more := q.More != nil && q.More(a, n)
+			confirm := Question{ID: q.ID, Type: QuestionBool, Key: q.Key, Prompt: q.Prompt, Help: q.Help}
+			input := f.Ask(&confirm, nil, boolString(more))
+			for input == "?" {
+				fmt.Print(questionHelp(&confirm, boolString(more)))
+				input = f.Ask(&confirm, nil, boolString(more))
+			}
+			if value, _ := confirm.normalize(input, nil, a); value != "true" {
+				return nil
+			}
+		}
+		item := newAnswers(a)
+		if err := askQuestions(f, q.Questions, item, itemProvided); err != nil {
+			return err
+		}
+		a.items[q.ID] = append(a.items[q.ID], item)
+		if q.Then != nil {
+			q.Then(item)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONSchema is the subset of JSON Schema draft-07 the config schema needs
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	Definitions          map[string]*JSONSchema `json:"definitions,omitempty"`
+}
+
+// schemaHeader makes the YAML language server validate the file against the schema at url
+func schemaHeader(url string) string {
+	return "# yaml-language-server: $schema=" + url + "\n"
+}
+
+// numericStringKeys are string fields so they can hold ${VAR} placeholders, which
+// Backstage also accepts as plain numbers
+var numericStringKeys = map[string]bool{
+	"backend.listen.port":              true,
+	"backend.database.connection.port": true,
+}
+
+// schemaEnums overrides the enums of keys where Backstage accepts more values
+// than the wizard offers, such as the providers set when importing a kubeconfig
+var schemaEnums = map[string][]string{
+	"kubernetes.clusterLocatorMethods.clusters.authProvider": {
+		"serviceAccount", "oidc", "aks", "aws", "azure", "google", "googleServiceAccount", "localKubectlProxy",
+	},
+}
+
+// requiredKeys are the keys the Backstage config schema itself requires. Other
+// keys the generator always writes stay optional, so hand-written configs
+// leaving them out still validate.
+var requiredKeys = map[string]bool{
+	"app":                         true,
+	"app.baseUrl":                 true,
+	"backend":                     true,
+	"backend.baseUrl":             true,
+	"backend.listen":              true,
+	"backend.database":            true,
+	"backend.database.client":     true,
+	"backend.database.connection": true,
+}
+
+type schemaBuilder struct {
+	definitions map[string]*JSONSchema
+	questions   map[string]*Question
+}
+
+// renderSchema reflects over Config and returns its JSON Schema. The keys
+// Backstage requires are required, the wizard's choices become enums and the
+// docs extracted into help.yaml become descriptions.
+func renderSchema() ([]byte, error) {
+	b := &schemaBuilder{
+		definitions: make(map[string]*JSONSchema),
+		questions:   make(map[string]*Question),
+	}
+	questionDefaults(func(q *Question, def string) {
+		if q.Key != "" && b.questions[q.Key] == nil {
+			b.questions[q.Key] = q
+		}
+	})
+
+	root := b.structSchema(reflect.TypeOf(Config{}), "")
+	root.Schema = "http://json-schema.org/draft-07/schema#"
+	root.Title = "Backstage app-config"
+	root.Definitions = b.definitions
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// typeSchema returns the schema of a value of type t found at the config key path
+func (b *schemaBuilder) typeSchema(t reflect.Type, path string) *JSONSchema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(DatabaseConnection{}) {
+		// Written as a plain string for better-sqlite3, see DatabaseConfig.MarshalYAML
+		return &JSONSchema{OneOf: []*JSONSchema{
+			{Type: "string", Description: ":memory: or the directory of the SQLite databases"},
+			b.namedSchema(reflect.TypeOf(databaseConnectionFields{}), "DatabaseConnection", path),
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return b.namedSchema(t, t.Name(), path)
+	case reflect.Slice:
+		return &JSONSchema{Type: "array", Items: b.typeSchema(t.Elem(), path)}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: b.typeSchema(t.Elem(), joinPath(path, "*"))}
+	case reflect.String:
+		if numericStringKeys[path] {
+			return &JSONSchema{Type: []string{"string", "integer"}}
+		}
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}
+	}
+	// interface{} values such as the auth providers can hold anything
+	return &JSONSchema{}
+}
+
+// namedSchema adds the struct to the definitions the first time it is seen and
+// refers to it. Structs used at several keys are described by the first one.
+func (b *schemaBuilder) namedSchema(t reflect.Type, name, path string) *JSONSchema {
+	if _, ok := b.definitions[name]; !ok {
+		b.definitions[name] = nil
+		b.definitions[name] = b.structSchema(t, path)
+	}
+	return &JSONSchema{Ref: "#/definitions/" + name}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type, path string) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name := yamlFieldName(field)
+		key := joinPath(path, name)
+
+		property := b.typeSchema(field.Type, key)
+		if property.Ref == "" {
+			property.Description = b.describe(key, field.Tag.Get("secret") == "true")
+			property.Enum = b.enum(key, field.Type)
+		}
+		// Unset sections without omitempty are written as null, and Backstage
+		// reads a section whose keys are all commented out as unset too
+		isStruct := field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct)
+		if (!strings.Contains(tag, ",omitempty") && field.Type.Kind() == reflect.Ptr) || (isStruct && !requiredKeys[key]) {
+			property = &JSONSchema{Description: property.Description, OneOf: []*JSONSchema{property, {Type: "null"}}}
+			property.OneOf[0].Description = ""
+		}
+		if requiredKeys[key] {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+func (b *schemaBuilder) question(key string) *Question {
+	if q, ok := b.questions[key]; ok {
+		return q
+	}
+	patterns := make([]string, 0, len(b.questions))
+	for pattern := range b.questions {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if keyMatches(pattern, key) {
+			return b.questions[pattern]
+		}
+	}
+	return nil
+}
+
+func (b *schemaBuilder) describe(key string, secret bool) string {
+	description := ""
+	if help, ok := lookupKeyHelp(key); ok {
+		description = help.Description
+	}
+	if q := b.question(key); description == "" && q != nil {
+		description = q.Help
+	}
+	if secret {
+		description = strings.TrimSpace(description + "\nSecret, use a ${VAR} placeholder instead of the value.")
+	}
+	return description
+}
+
+// enum lists the values of the wizard's choice question for key, typed like the field
+func (b *schemaBuilder) enum(key string, t reflect.Type) []interface{} {
+	options, ok := schemaEnums[key]
+	if !ok {
+		q := b.question(key)
+		if q == nil || q.Type != QuestionChoice {
+			return nil
+		}
+		options = q.Options
+	}
+	var values []interface{}
+	for _, option := range options {
+		if t.Kind() == reflect.Int {
+			n, err := strconv.Atoi(option)
+			if err != nil {
+				return nil
+			}
+			values = append(values, n)
+		} else {
+			values = append(values, option)
+		}
+	}
+	return values
+}
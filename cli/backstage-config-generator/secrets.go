@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SecretField is a non-empty value of a field tagged with `secret:"true"`
+type SecretField struct {
+	Path  string
+	Value string
+}
+
+// untaggedSecretKeys covers secrets stored in maps, which cannot carry struct tags
+var untaggedSecretKeys = map[string]bool{
+	"clientSecret":  true,
+	"Authorization": true,
+}
+
+// secretFields returns every secret value in v, addressed by its YAML key path
+func secretFields(v interface{}) []SecretField {
+	var fields []SecretField
+	walkSecrets(v, func(path, value string) string {
+		fields = append(fields, SecretField{Path: path, Value: value})
+		return value
+	})
+	return fields
+}
+
+func plaintextSecrets(fields []SecretField) []SecretField {
+	var plaintext []SecretField
+	for _, field := range fields {
+		if !isPlaceholder(field.Value) && !isSecretRef(field.Value) {
+			plaintext = append(plaintext, field)
+		}
+	}
+	return plaintext
+}
+
+func yamlFieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// walkSecrets calls fn for every non-empty secret value in v and stores the value
+// fn returns. v must be a pointer for the stored values to take effect.
+func walkSecrets(v interface{}, fn func(path, value string) string) {
+	walkSecretValue(reflect.ValueOf(v), "", false, fn)
+}
+
+func walkSecretValue(v reflect.Value, path string, secret bool, fn func(path, value string) string) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkSecretValue(v.Elem(), path, secret, fn)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		if elem := v.Elem(); elem.Kind() == reflect.String {
+			if secret && elem.String() != "" {
+				value := fn(path, elem.String())
+				if v.CanSet() {
+					v.Set(reflect.ValueOf(value))
+				}
+			}
+			return
+		}
+		walkSecretValue(v.Elem(), path, secret, fn)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := yamlFieldName(field)
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			walkSecretValue(v.Field(i), joinPath(path, name), field.Tag.Get("secret") == "true", fn)
+		}
+	case reflect.Map:
+		var keys []string
+		for _, key := range v.MapKeys() {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			// Map values are not addressable, walk a copy and store it back
+			mapKey := reflect.ValueOf(key).Convert(v.Type().Key())
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(mapKey))
+			walkSecretValue(value, joinPath(path, key), secret || untaggedSecretKeys[key], fn)
+			v.SetMapIndex(mapKey, value)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkSecretValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), secret, fn)
+		}
+	case reflect.String:
+		if secret && v.String() != "" {
+			value := fn(path, v.String())
+			if v.CanSet() {
+				v.SetString(value)
+			}
+		}
+	}
+}
+
+// writeFileAtomic writes to a temporary file next to path and renames it into
+// place, so readers never see a partially written config.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// isGitTracked reports whether path is tracked in a git repository
+func isGitTracked(path string) bool {
+	cmd := exec.Command("git", "ls-files", "--error-unmatch", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdout, cmd.Stderr = nil, nil
+	return cmd.Run() == nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretSource resolves secret references of the form <scheme>://<location>
+type SecretSource interface {
+	Scheme() string
+	Resolve(location string) (string, error)
+}
+
+var secretSources = map[string]SecretSource{}
+
+func registerSecretSource(source SecretSource) {
+	secretSources[source.Scheme()] = source
+}
+
+func init() {
+	registerSecretSource(envSource{})
+	registerSecretSource(fileSource{})
+	registerSecretSource(newVaultSource())
+}
+
+// parseSecretRef splits a reference such as env://GITHUB_TOKEN into its scheme and location
+func parseSecretRef(value string) (scheme, location string, ok bool) {
+	scheme, location, ok = strings.Cut(value, "://")
+	if !ok {
+		return "", "", false
+	}
+	_, registered := secretSources[scheme]
+	return scheme, location, registered
+}
+
+func isSecretRef(value string) bool {
+	_, _, ok := parseSecretRef(value)
+	return ok
+}
+
+type envSource struct{}
+
+func (envSource) Scheme() string { return "env" }
+
+func (envSource) Resolve(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+type fileSource struct{}
+
+func (fileSource) Scheme() string { return "file" }
+
+func (fileSource) Resolve(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// vaultSource reads keys from a KV secrets engine, references look like
+// vault://<mount>/<path>#<key>. VAULT_ADDR and VAULT_TOKEN select the server.
+type vaultSource struct {
+	client *http.Client
+}
+
+func newVaultSource() vaultSource {
+	return vaultSource{client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (vaultSource) Scheme() string { return "vault" }
+
+func (v vaultSource) Resolve(location string) (string, error) {
+	secretPath, key, ok := strings.Cut(location, "#")
+	if !ok || key == "" {
+		return "", fmt.Errorf("vault reference %s is missing a #key", location)
+	}
+	mount, path, ok := strings.Cut(secretPath, "/")
+	if !ok {
+		return "", fmt.Errorf("vault reference %s must include a mount and a path", location)
+	}
+	addr := os.Getenv("VAULT_ADDR")
+	if addr == "" {
+		addr = "http://127.0.0.1:8200"
+	}
+
+	// KV v2 nests the secret under data.data, KV v1 under data. Only a 404 means
+	// the mount is not a KV v2 one, other errors such as a denied token are final.
+	data, err := v.read(fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(addr, "/"), mount, path))
+	var status vaultStatusError
+	switch {
+	case err == nil:
+		if nested, ok := data["data"].(map[string]interface{}); ok {
+			data = nested
+		}
+	case errors.As(err, &status) && status.Code == http.StatusNotFound:
+		if data, err = v.read(fmt.Sprintf("%s/v1/%s/%s", strings.TrimRight(addr, "/"), mount, path)); err != nil {
+			return "", err
+		}
+	default:
+		return "", err
+	}
+
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("key %s not found in vault secret %s", key, secretPath)
+	}
+	return value, nil
+}
+
+// vaultStatusError is returned when vault answers with another status than 200
+type vaultStatusError struct {
+	Code   int
+	Status string
+	URL    string
+}
+
+func (e vaultStatusError) Error() string {
+	return fmt.Sprintf("vault returned %s for %s", e.Status, e.URL)
+}
+
+func (v vaultSource) read(url string) (map[string]interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", os.Getenv("VAULT_TOKEN"))
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, vaultStatusError{Code: resp.StatusCode, Status: resp.Status, URL: url}
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return body.Data, nil
+}
+
+// resolveSecretRefs replaces every secret reference in config with the value from its source
+func resolveSecretRefs(config *Config) error {
+	var errs []string
+	walkSecrets(config, func(path, value string) string {
+		scheme, location, ok := parseSecretRef(value)
+		if !ok {
+			return value
+		}
+		resolved, err := secretSources[scheme].Resolve(location)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", path, err))
+			return value
+		}
+		return resolved
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("resolving secrets:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// secretIncludes maps the path of every unresolved secret reference to the
+// Backstage $env or $file include that loads it at runtime. Backstage cannot
+// read vault, so vault references have to be resolved first.
+func secretIncludes(config *Config) (map[string]*yaml.Node, error) {
+	includes := make(map[string]*yaml.Node)
+	var vaultPaths []string
+	for _, secret := range secretFields(config) {
+		scheme, location, ok := parseSecretRef(secret.Value)
+		if !ok {
+			continue
+		}
+		key, value := "$env", location
+		switch scheme {
+		case "file":
+			key = "$file"
+		case "vault":
+			vaultPaths = append(vaultPaths, secret.Path)
+			continue
+		}
+		includes[secret.Path] = &yaml.Node{
+			Kind: yaml.MappingNode,
+			Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: key},
+				{Kind: yaml.ScalarNode, Value: value},
+			},
+		}
+	}
+	if len(vaultPaths) > 0 {
+		return nil, fmt.Errorf("%s reference vault, use --resolve-secrets or an env:// reference read at runtime", strings.Join(vaultPaths, ", "))
+	}
+	return includes, nil
+}
+
+// marshalConfig renders config as YAML, writing unresolved secret references as includes
+func marshalConfig(config *Config) ([]byte, error) {
+	var root yaml.Node
+	if err := root.Encode(config); err != nil {
+		return nil, err
+	}
+	includes, err := secretIncludes(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(includes) > 0 {
+		replaceNodes(&root, "", includes)
+	}
+	return yaml.Marshal(&root)
+}
+
+func replaceNodes(node *yaml.Node, path string, replacements map[string]*yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			replaceNodes(child, path, replacements)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childPath := joinPath(path, node.Content[i].Value)
+			if replacement, ok := replacements[childPath]; ok {
+				node.Content[i+1] = replacement
+				continue
+			}
+			replaceNodes(node.Content[i+1], childPath, replacements)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			replaceNodes(child, fmt.Sprintf("%s[%d]", path, i), replacements)
+		}
+	}
+}
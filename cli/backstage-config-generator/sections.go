@@ -0,0 +1,995 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+func configSections(kubeconfigPath string) []Section {
+	return []Section{
+		appSection,
+		backendSection,
+		authSection,
+		integrationsSection,
+		catalogSection,
+		techdocsSection,
+		kubernetesSection(kubeconfigPath),
+		kubernetesIngestorSection,
+		scaleopsSection,
+		proxySection,
+		devpodSection,
+		permissionSection,
+		crossplaneSection,
+		kyvernoSection,
+		vcfAutomationSection,
+		// Scaffolder runs last, it reads the Kubernetes Ingestor answers
+		scaffolderSection,
+	}
+}
+
+var appSection = Section{
+	ID:    "app",
+	Title: "General App",
+	Questions: []Question{
+		{ID: "title", Key: "app.title", Prompt: "Enter application title", DefaultFrom: func(a *Answers) string { return defaults.App.Title },
+			Help: "Title shown in the browser tab and the sidebar of the Backstage app."},
+		{ID: "baseUrl", Key: "app.baseUrl", Prompt: "Enter frontend base URL", DefaultFrom: func(a *Answers) string { return defaults.App.BaseUrl }, Validate: validateURL,
+			Help: "URL users open the Backstage app on."},
+		{ID: "organization", Key: "organization.name", Prompt: "Enter organization name", DefaultFrom: func(a *Answers) string { return defaults.Organization.Name },
+			Help: "Organization name shown in the app."},
+	},
+	Build: func(a *Answers, config *Config) {
+		config.App = AppConfig{Title: a.Get("title"), BaseUrl: a.Get("baseUrl")}
+		config.Organization = OrgConfig{Name: a.Get("organization")}
+	},
+}
+
+var backendSection = Section{
+	ID:    "backend",
+	Title: "Backend",
+	Questions: []Question{
+		{ID: "port", Key: "backend.listen.port", Prompt: "Enter backend port", DefaultFrom: func(a *Answers) string { return defaults.Backend.Port }, Validate: validatePort,
+			Help: "Port the backend listens on."},
+		{ID: "baseUrl", Key: "backend.baseUrl", Prompt: "Enter backend base URL", DefaultFrom: func(a *Answers) string { return "http://localhost:" + a.Get("port") }, Validate: validateURL,
+			Help: "URL the frontend and integrations reach the backend on."},
+		{ID: "database", Key: "backend.database.client", Type: QuestionChoice, Prompt: "Enter database client", Options: []string{"better-sqlite3", "pg"},
+			DefaultFrom: func(a *Answers) string { return defaults.Backend.Database },
+			Help:        "better-sqlite3 for local development, pg (PostgreSQL) for anything that must keep its data."},
+		{ID: "sqlitePath", Key: "backend.database.connection", Prompt: "Enter SQLite storage directory (:memory: for in-memory)", Default: ":memory:", DependsOn: whenEquals("database", "better-sqlite3"),
+			Help: "Directory for the SQLite database files. An in-memory database loses all data on restart."},
+		{Type: QuestionGroup, DependsOn: whenEquals("database", "pg"), Questions: []Question{
+			{ID: "postgresHost", Key: "backend.database.connection.host", Prompt: "Enter PostgreSQL host", Default: "localhost"},
+			{ID: "postgresPort", Key: "backend.database.connection.port", Prompt: "Enter PostgreSQL port", Default: "5432", Validate: validatePort},
+			{ID: "postgresUser", Key: "backend.database.connection.user", Prompt: "Enter PostgreSQL user", Default: "backstage"},
+			{ID: "postgresPassword", Key: "backend.database.connection.password", Prompt: "Enter PostgreSQL password", Default: "${POSTGRES_PASSWORD}",
+				Help: "Keep the ${POSTGRES_PASSWORD} placeholder to read the password from the environment."},
+		}},
+	},
+	Build: func(a *Answers, config *Config) {
+		database := DatabaseConfig{Client: a.Get("database")}
+		if database.Client == "pg" {
+			database.Connection = DatabaseConnection{
+				Host:     a.Get("postgresHost"),
+				Port:     a.Get("postgresPort"),
+				User:     a.Get("postgresUser"),
+				Password: a.Get("postgresPassword"),
+			}
+		} else {
+			database.Connection.Path = a.Get("sqlitePath")
+		}
+
+		config.Backend = BackendConfig{
+			BaseUrl: a.Get("baseUrl"),
+			Listen: ListenConfig{
+				Port: a.Get("port"),
+			},
+			CSP: CSPConfig{
+				ConnectSrc: []string{"'self'", "http:", "https:"},
+			},
+			CORS: CORSConfig{
+				Origin:      "http://localhost:3000",
+				Methods:     []string{"GET", "HEAD", "PATCH", "POST", "PUT", "DELETE"},
+				Credentials: true,
+			},
+			Database: database,
+			Reading: ReadingConfig{
+				Allow: []AllowConfig{
+					{Host: "raw.githubusercontent.com"},
+				},
+			},
+		}
+	},
+}
+
+var authSection = Section{
+	ID:    "authentication",
+	Title: "Authentication",
+	Questions: []Question{
+		{ID: "microsoft", Key: "auth.providers.microsoft", Type: QuestionBool, Prompt: "Configure Microsoft authentication?", Preset: "microsoftAuth",
+			Help: "Sign in with Microsoft Entra ID."},
+		{Type: QuestionGroup, DependsOn: when("microsoft"), Questions: []Question{
+			{ID: "microsoftClientId", Key: "auth.providers.microsoft.development.clientId", Prompt: "Enter Microsoft client ID"},
+			{ID: "microsoftClientSecret", Key: "auth.providers.microsoft.development.clientSecret", Prompt: "Enter Microsoft client secret", Help: "Use a ${VAR} placeholder to keep the secret out of the file."},
+			{ID: "microsoftTenantId", Key: "auth.providers.microsoft.development.tenantId", Prompt: "Enter Microsoft tenant ID"},
+			{ID: "microsoftDomainHint", Key: "auth.providers.microsoft.development.domainHint", Prompt: "Enter Microsoft domain hint"},
+		}},
+		{ID: "github", Key: "auth.providers.github", Type: QuestionBool, Prompt: "Configure GitHub authentication?", Preset: "githubAuth",
+			Help: "Sign in with a GitHub OAuth app."},
+		{Type: QuestionGroup, DependsOn: when("github"), Questions: []Question{
+			{ID: "githubClientId", Key: "auth.providers.github.development.clientId", Prompt: "Enter GitHub client ID"},
+			{ID: "githubClientSecret", Key: "auth.providers.github.development.clientSecret", Prompt: "Enter GitHub client secret", Help: "Use a ${VAR} placeholder to keep the secret out of the file."},
+		}},
+	},
+	Build: func(a *Answers, config *Config) {
+		providers := make(map[string]interface{})
+		if a.Bool("microsoft") {
+			providers["microsoft"] = map[string]interface{}{
+				"development": map[string]interface{}{
+					"clientId":     a.Get("microsoftClientId"),
+					"clientSecret": a.Get("microsoftClientSecret"),
+					"tenantId":     a.Get("microsoftTenantId"),
+					"domainHint":   a.Get("microsoftDomainHint"),
+				},
+			}
+		}
+		if a.Bool("github") {
+			providers["github"] = map[string]interface{}{
+				"development": map[string]interface{}{
+					"clientId":     a.Get("githubClientId"),
+					"clientSecret": a.Get("githubClientSecret"),
+				},
+			}
+		}
+		config.Auth = AuthConfig{
+			Environment: "development",
+			Providers:   providers,
+		}
+	},
+}
+
+var integrationsSection = Section{
+	ID:    "integrations",
+	Title: "Source Control Integration",
+	Questions: []Question{
+		{ID: "github", Key: "integrations.github", Type: QuestionBool, Prompt: "Configure GitHub integration?", Default: "true", Preset: "github",
+			Help: "Lets the catalog and scaffolder read from and publish to github.com."},
+		{ID: "githubToken", Key: "integrations.github.token", Prompt: "Enter GitHub PAT", DependsOn: when("github"),
+			Help: "Personal access token, use a ${VAR} placeholder to keep it out of the file."},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("github") {
+			config.Integrations = IntegrationsConfig{}
+			return
+		}
+		config.Integrations = IntegrationsConfig{
+			Github: []GithubIntegrationConfig{
+				{
+					Host:  "github.com",
+					Token: a.Get("githubToken"),
+				},
+			},
+		}
+	},
+}
+
+var catalogSection = Section{
+	ID:    "catalog",
+	Title: "Catalog",
+	Questions: []Question{
+		{ID: "microsoftGraph", Key: "catalog.providers.microsoftGraphOrg", Type: QuestionBool, Prompt: "Configure Microsoft Graph integration?", Preset: "microsoftGraph",
+			Help: "Ingests users and groups from Microsoft Entra ID into the catalog."},
+		{Type: QuestionGroup, DependsOn: when("microsoftGraph"), Questions: []Question{
+			{ID: "clientId", Key: "catalog.providers.microsoftGraphOrg.*.clientId", Prompt: "Enter Microsoft Graph client ID"},
+			{ID: "clientSecret", Key: "catalog.providers.microsoftGraphOrg.*.clientSecret", Prompt: "Enter Microsoft Graph client secret", Help: "Use a ${VAR} placeholder to keep the secret out of the file."},
+			{ID: "tenantId", Key: "catalog.providers.microsoftGraphOrg.*.tenantId", Prompt: "Enter Microsoft Graph tenant ID"},
+		}},
+	},
+	Build: func(a *Answers, config *Config) {
+		msGraphConfig := make(map[string]MSGraphConfig)
+		if a.Bool("microsoftGraph") {
+			msGraphConfig["default"] = MSGraphConfig{
+				ClientId:     a.Get("clientId"),
+				ClientSecret: a.Get("clientSecret"),
+				TenantId:     a.Get("tenantId"),
+				User: MSGraphUserConfig{
+					Filter: "accountEnabled eq true and userType eq 'member'",
+				},
+				Schedule: MSGraphScheduleConfig{
+					Frequency: "PT1H",
+					Timeout:   "PT50M",
+				},
+			}
+		}
+
+		config.Catalog = CatalogConfig{
+			Providers: CatalogProvidersConfig{
+				MicrosoftGraphOrg: msGraphConfig,
+			},
+			Import: ImportConfig{
+				EntityFilename:        "catalog-info.yaml",
+				PullRequestBranchName: "backstage-integration",
+			},
+			Rules: []RuleConfig{
+				{Allow: []string{"Component", "System", "API", "Resource", "Location", "Template"}},
+			},
+			Locations: []LocationConfig{
+				{
+					Type:   "file",
+					Target: "../../examples/entities.yaml",
+				},
+				{
+					Type:   "file",
+					Target: "../../examples/template/template.yaml",
+					Rules:  []RuleConfig{{Allow: []string{"Template"}}},
+				},
+				{
+					Type:   "file",
+					Target: "../../examples/org.yaml",
+					Rules:  []RuleConfig{{Allow: []string{"User", "Group"}}},
+				},
+			},
+		}
+	},
+}
+
+var techdocsSection = Section{
+	ID:    "techdocs",
+	Title: "TechDocs",
+	Questions: []Question{
+		{ID: "builder", Key: "techdocs.builder", Type: QuestionChoice, Prompt: "Enter TechDocs builder", Options: []string{"local", "external"},
+			DefaultFrom: func(a *Answers) string { return defaults.Techdocs.Builder },
+			Help:        "local builds docs on demand in the backend, external serves docs built by CI."},
+		{ID: "runIn", Key: "techdocs.generator.runIn", Type: QuestionChoice, Prompt: "Run the generator in", Options: []string{"docker", "local"}, DependsOn: whenEquals("builder", "local"),
+			DefaultFrom: func(a *Answers) string { return defaults.Techdocs.RunIn },
+			Help:        "docker runs mkdocs in a container, local needs mkdocs-techdocs-core installed next to the backend."},
+		{ID: "publisher", Key: "techdocs.publisher.type", Type: QuestionChoice, Prompt: "Enter TechDocs publisher", Options: []string{"local", "awsS3", "googleGcs", "azureBlobStorage"},
+			DefaultFrom: func(a *Answers) string { return defaults.Techdocs.Publisher },
+			Help:        "Where generated docs are stored."},
+		{Type: QuestionGroup, DependsOn: whenEquals("publisher", "awsS3"), Questions: []Question{
+			{ID: "s3BucketName", Prompt: "Enter S3 bucket name"},
+			{ID: "s3Region", Prompt: "Enter S3 region", DefaultFrom: func(a *Answers) string { return defaults.Techdocs.S3Region }},
+			{ID: "s3Endpoint", Prompt: "Enter S3 endpoint for S3-compatible stores (empty for AWS)", Validate: validateURL},
+			{ID: "s3ForcePathStyle", Type: QuestionBool, Prompt: "Force path-style bucket URLs?",
+				Help: "Needed by most S3-compatible stores such as MinIO."},
+		}},
+		{ID: "gcsBucketName", Key: "techdocs.publisher.googleGcs.bucketName", Prompt: "Enter GCS bucket name", DependsOn: whenEquals("publisher", "googleGcs")},
+		{Type: QuestionGroup, DependsOn: whenEquals("publisher", "azureBlobStorage"), Questions: []Question{
+			{ID: "azureContainerName", Key: "techdocs.publisher.azureBlobStorage.containerName", Prompt: "Enter Azure Blob Storage container name"},
+			{ID: "azureAccountName", Key: "techdocs.publisher.azureBlobStorage.credentials.accountName", Prompt: "Enter Azure storage account name"},
+		}},
+		{ID: "cache", Key: "techdocs.cache", Type: QuestionBool, Prompt: "Enable TechDocs cache?", Default: "true", DependsOn: func(a *Answers) bool { return a.Get("publisher") != "local" },
+			Help: "Caches docs fetched from the publisher in the backend."},
+	},
+	Build: func(a *Answers, config *Config) {
+		techdocs := TechdocsConfig{Builder: a.Get("builder")}
+		if techdocs.Builder == "local" {
+			techdocs.Generator = &GeneratorConfig{RunIn: a.Get("runIn")}
+		}
+
+		techdocs.Publisher.Type = a.Get("publisher")
+		switch techdocs.Publisher.Type {
+		case "awsS3":
+			techdocs.Publisher.AwsS3 = &AwsS3PublisherConfig{
+				BucketName:       a.Get("s3BucketName"),
+				Region:           a.Get("s3Region"),
+				Endpoint:         a.Get("s3Endpoint"),
+				S3ForcePathStyle: a.Bool("s3ForcePathStyle"),
+				Credentials: AwsS3Credentials{
+					AccessKeyId:     "${AWS_ACCESS_KEY_ID}",
+					SecretAccessKey: "${AWS_SECRET_ACCESS_KEY}",
+				},
+			}
+		case "googleGcs":
+			techdocs.Publisher.GoogleGcs = &GcsPublisherConfig{
+				BucketName:  a.Get("gcsBucketName"),
+				Credentials: "${GOOGLE_APPLICATION_CREDENTIALS}",
+			}
+		case "azureBlobStorage":
+			techdocs.Publisher.AzureBlobStorage = &AzureBlobPublisherConfig{
+				ContainerName: a.Get("azureContainerName"),
+				Credentials: AzureBlobCredentials{
+					AccountName: a.Get("azureAccountName"),
+					AccountKey:  "${TECHDOCS_AZURE_BLOB_STORAGE_ACCOUNT_KEY}",
+				},
+			}
+		}
+
+		if a.Bool("cache") {
+			techdocs.Cache = &TechdocsCacheConfig{
+				TTL:         3600000,
+				ReadTimeout: 500,
+			}
+		}
+		config.Techdocs = techdocs
+	},
+}
+
+var clusterQuestions = []Question{
+	{ID: "name", Key: "kubernetes.clusterLocatorMethods.clusters.name", Prompt: "Enter cluster name"},
+	{ID: "url", Key: "kubernetes.clusterLocatorMethods.clusters.url", Prompt: "Enter cluster URL", Validate: validateURL, Help: "URL of the cluster API server."},
+	{ID: "authProvider", Key: "kubernetes.clusterLocatorMethods.clusters.authProvider", Type: QuestionChoice, Prompt: "Enter auth provider", Options: []string{"serviceAccount", "oidc"},
+		DefaultFrom: func(a *Answers) string { return defaults.Kubernetes.AuthProvider },
+		Help:        "serviceAccount uses a token for all users, oidc forwards the signed-in user's token."},
+	{ID: "skipTLSVerify", Key: "kubernetes.clusterLocatorMethods.clusters.skipTLSVerify", Type: QuestionBool, Prompt: "Skip TLS verification?",
+		Help: "Only for clusters with self-signed certificates, it is dropped unless the CA is provided too."},
+	{ID: "serviceAccountToken", Key: "kubernetes.clusterLocatorMethods.clusters.serviceAccountToken", Prompt: "Enter service account token", DependsOn: whenEquals("authProvider", "serviceAccount"),
+		Help: "Use a ${VAR} placeholder to keep the token out of the file."},
+	{ID: "oidcTokenProvider", Key: "kubernetes.clusterLocatorMethods.clusters.oidcTokenProvider", Prompt: "Enter OIDC token provider (microsoft/okta/google/gitlab)", DependsOn: whenEquals("authProvider", "oidc"),
+		DefaultFrom: func(a *Answers) string { return defaults.Kubernetes.OidcTokenProvider }},
+	{ID: "caData", Key: "kubernetes.clusterLocatorMethods.clusters.caData", Prompt: "Enter cluster CA data (base64, empty to skip)"},
+	{ID: "caFile", Key: "kubernetes.clusterLocatorMethods.clusters.caFile", Prompt: "Enter cluster CA file path (empty to skip)", DependsOn: func(a *Answers) bool { return a.Get("caData") == "" }},
+	{ID: "dashboard", Type: QuestionBool, Prompt: "Link cluster to a dashboard?"},
+	{Type: QuestionGroup, DependsOn: when("dashboard"), Questions: []Question{
+		{ID: "dashboardApp", Key: "kubernetes.clusterLocatorMethods.clusters.dashboardApp", Type: QuestionChoice, Prompt: "Enter dashboard app", Options: []string{"standard", "rancher", "openshift", "aks", "eks", "gke"}, Default: "standard"},
+		{ID: "dashboardUrl", Key: "kubernetes.clusterLocatorMethods.clusters.dashboardUrl", Prompt: "Enter dashboard URL", Validate: validateURL},
+	}},
+	{ID: "customResources", Key: "kubernetes.clusterLocatorMethods.clusters.customResources", Type: QuestionRepeat, Prompt: "Add a custom resource for this cluster?", Questions: []Question{
+		{ID: "group", Prompt: "Enter group"},
+		{ID: "apiVersion", Prompt: "Enter API version"},
+		{ID: "plural", Prompt: "Enter plural"},
+	}},
+}
+
+func kubernetesSection(kubeconfigPath string) Section {
+	kubeconfig := func(a *Answers) string {
+		if kubeconfigPath != "" {
+			return kubeconfigPath
+		}
+		return a.Get("kubeconfig")
+	}
+
+	return Section{
+		ID:    "kubernetes",
+		Title: "Kubernetes",
+		Questions: []Question{
+			{ID: "enabled", Key: "kubernetes", Type: QuestionBool, Prompt: "Configure Kubernetes integration?", Preset: "kubernetes",
+				Help: "Shows the Kubernetes resources of catalog entities."},
+			{ID: "locators", Key: "kubernetes.clusterLocatorMethods", Type: QuestionRepeat, Prompt: "Add a cluster locator method?", DependsOn: when("enabled"),
+				More: func(a *Answers, n int) bool { return n == 0 },
+				Questions: []Question{
+					{ID: "type", Key: "kubernetes.clusterLocatorMethods.type", Type: QuestionChoice, Prompt: "Enter cluster locator type", Options: []string{"config", "catalog", "localKubectlProxy", "gke"}, Default: "config",
+						Help: "config lists clusters in the app-config, catalog reads Resource entities, gke discovers GKE clusters."},
+					{Type: QuestionGroup, DependsOn: whenEquals("type", "config"), Questions: []Question{
+						{ID: "importKubeconfig", Type: QuestionBool, Prompt: "Import clusters from a kubeconfig file?", DependsOn: func(a *Answers) bool { return kubeconfigPath == "" }},
+						{ID: "kubeconfig", Prompt: "Enter kubeconfig path", DefaultFrom: func(a *Answers) string { return defaultKubeconfigPath() },
+							DependsOn: func(a *Answers) bool { return kubeconfigPath == "" && a.Bool("importKubeconfig") }},
+						{ID: "contexts", Type: QuestionMultiSelect, Prompt: "Select kubeconfig contexts to import",
+							DependsOn:   func(a *Answers) bool { return kubeconfig(a) != "" },
+							OptionsFrom: func(a *Answers) []string { return kubeconfigContexts(kubeconfig(a)) }},
+						{ID: "clusters", Key: "kubernetes.clusterLocatorMethods.clusters", Type: QuestionRepeat, Prompt: "Add a Kubernetes cluster?", Questions: clusterQuestions,
+							More: func(a *Answers, n int) bool { return n == 0 && len(a.List("contexts")) == 0 }},
+					}},
+					{Type: QuestionGroup, DependsOn: whenEquals("type", "gke"), Questions: []Question{
+						{ID: "projectId", Key: "kubernetes.clusterLocatorMethods.projectId", Prompt: "Enter GCP project ID"},
+						{ID: "region", Key: "kubernetes.clusterLocatorMethods.region", Prompt: "Enter GKE region (empty for all regions)"},
+						{ID: "skipTLSVerify", Key: "kubernetes.clusterLocatorMethods.skipTLSVerify", Type: QuestionBool, Prompt: "Skip TLS verification?"},
+						{ID: "skipMetricsLookup", Key: "kubernetes.clusterLocatorMethods.skipMetricsLookup", Type: QuestionBool, Prompt: "Skip metrics lookup?"},
+						{ID: "exposeDashboard", Key: "kubernetes.clusterLocatorMethods.exposeDashboard", Type: QuestionBool, Prompt: "Expose GKE dashboard links?"},
+					}},
+				}},
+		},
+		Build: func(a *Answers, config *Config) {
+			if !a.Bool("enabled") {
+				config.Kubernetes = nil
+				return
+			}
+
+			var locators []ClusterLocatorMethodConfig
+			for _, locator := range a.Items("locators") {
+				switch locator.Get("type") {
+				case "config":
+					var clusters []ClusterConfig
+					if contexts := locator.List("contexts"); len(contexts) > 0 {
+						clusters = importKubeconfigClusters(kubeconfig(locator), contexts)
+					}
+					for _, cluster := range locator.Items("clusters") {
+						clusters = append(clusters, buildCluster(cluster))
+					}
+					locators = append(locators, ClusterLocatorMethodConfig{Type: "config", Clusters: clusters})
+				case "gke":
+					locators = append(locators, ClusterLocatorMethodConfig{
+						Type:              "gke",
+						ProjectId:         locator.Get("projectId"),
+						Region:            locator.Get("region"),
+						SkipTLSVerify:     locator.Bool("skipTLSVerify"),
+						SkipMetricsLookup: locator.Bool("skipMetricsLookup"),
+						ExposeDashboard:   locator.Bool("exposeDashboard"),
+					})
+				default:
+					locators = append(locators, ClusterLocatorMethodConfig{Type: locator.Get("type")})
+				}
+			}
+
+			config.Kubernetes = &KubernetesConfig{
+				Frontend: K8sFrontendConfig{
+					PodDelete: PodDeleteConfig{
+						Enabled: true,
+					},
+				},
+				ServiceLocatorMethod: ServiceLocatorMethodConfig{
+					Type: "multiTenant",
+				},
+				ClusterLocatorMethods: locators,
+			}
+		},
+	}
+}
+
+// dropSkipTLSVerifyWithoutCA never writes skipTLSVerify for a cluster without a
+// CA, which would leave its API server entirely unverified
+func dropSkipTLSVerifyWithoutCA(cluster *ClusterConfig) {
+	if cluster.SkipTLSVerify && cluster.CAData == "" && cluster.CAFile == "" {
+		fmt.Fprintf(os.Stderr, "Warning: dropped skipTLSVerify for cluster %s, set caData or caFile to use it\n", cluster.Name)
+		cluster.SkipTLSVerify = false
+	}
+}
+
+func buildCluster(a *Answers) ClusterConfig {
+	cluster := ClusterConfig{
+		Name:                a.Get("name"),
+		Url:                 a.Get("url"),
+		AuthProvider:        a.Get("authProvider"),
+		SkipTLSVerify:       a.Bool("skipTLSVerify"),
+		ServiceAccountToken: a.Get("serviceAccountToken"),
+		OidcTokenProvider:   a.Get("oidcTokenProvider"),
+		CAData:              a.Get("caData"),
+		CAFile:              a.Get("caFile"),
+		DashboardApp:        a.Get("dashboardApp"),
+		DashboardUrl:        a.Get("dashboardUrl"),
+	}
+	dropSkipTLSVerifyWithoutCA(&cluster)
+	for _, resource := range a.Items("customResources") {
+		cluster.CustomResources = append(cluster.CustomResources, CustomResourceConfig{
+			Group:      resource.Get("group"),
+			ApiVersion: resource.Get("apiVersion"),
+			Plural:     resource.Get("plural"),
+		})
+	}
+	return cluster
+}
+
+var kubernetesIngestorSection = Section{
+	ID:    "kubernetesIngestor",
+	Title: "Kubernetes Ingestor",
+	Questions: []Question{
+		{ID: "enabled", Key: "kubernetesIngestor", Type: QuestionBool, Prompt: "Configure Kubernetes Ingestor?", Preset: "kubernetesIngestor",
+			Help: "Creates catalog entities from workloads, Crossplane claims and XRDs running in the clusters."},
+		{Type: QuestionGroup, DependsOn: when("enabled"), Questions: []Question{
+			{ID: "namespaceModel", Key: "kubernetesIngestor.mappings.namespaceModel", Type: QuestionChoice, Prompt: "Enter namespace model", Options: []string{"cluster", "namespace", "default"},
+				DefaultFrom: func(a *Answers) string { return defaults.KubernetesIngestor.Mappings.NamespaceModel },
+				Heading:     "Kubernetes To Backstage Mappings Configurations",
+				Help:        "Which Backstage namespace an entity is created in: one per cluster, the resource's namespace, or default."},
+			{ID: "nameModel", Key: "kubernetesIngestor.mappings.nameModel", Type: QuestionChoice, Prompt: "Enter name model", Options: []string{"name-cluster", "name-namespace", "name-kind", "name"},
+				DefaultFrom: func(a *Answers) string { return defaults.KubernetesIngestor.Mappings.NameModel },
+				Help:        "How the entity name is built from the resource name, to keep names unique across clusters and namespaces."},
+			{ID: "titleModel", Key: "kubernetesIngestor.mappings.titleModel", Type: QuestionChoice, Prompt: "Enter title model", Options: []string{"name", "name-cluster", "name-namespace"},
+				DefaultFrom: func(a *Answers) string { return defaults.KubernetesIngestor.Mappings.TitleModel },
+				Help:        "How the entity title shown in the catalog is built."},
+			{ID: "systemModel", Key: "kubernetesIngestor.mappings.systemModel", Type: QuestionChoice, Prompt: "Enter system model", Options: []string{"cluster", "namespace", "cluster-namespace", "default"},
+				DefaultFrom: func(a *Answers) string { return defaults.KubernetesIngestor.Mappings.SystemModel },
+				Help:        "Which system entities are grouped under."},
+			{ID: "referencesNamespaceModel", Key: "kubernetesIngestor.mappings.referencesNamespaceModel", Type: QuestionChoice, Prompt: "Enter references namespace model", Options: []string{"default", "same"},
+				DefaultFrom: func(a *Answers) string { return defaults.KubernetesIngestor.Mappings.ReferencesNamespaceModel },
+				Help:        "Namespace used for owner and system references: default, or the same as the entity."},
+			{ID: "mappingPreviews", Type: QuestionRepeat, Prompt: "Preview the mappings on a sample workload?",
+				Help: "Shows the entity ref, title and system the ingestor creates for a workload with the models above.",
+				Questions: []Question{
+					{ID: "manifest", Prompt: "Enter sample manifest file (empty to enter the workload by hand)", Validate: validateManifest},
+					{Type: QuestionGroup, DependsOn: whenEquals("manifest", ""), Questions: []Question{
+						{ID: "name", Prompt: "Enter workload name", Default: "my-app"},
+						{ID: "namespace", Prompt: "Enter workload namespace", Default: "default"},
+						{ID: "kind", Prompt: "Enter workload kind", Default: "Deployment"},
+					}},
+					{ID: "cluster", Prompt: "Enter cluster name", DefaultFrom: sampleClusterName},
+				},
+				Then: showMappingPreview},
+			{ID: "components", Key: "kubernetesIngestor.components.enabled", Type: QuestionBool, Prompt: "Enable components?", Default: "true",
+				Heading: "Kubernetes Workloads Component Generation Configurations"},
+			{ID: "excludedNamespaces", Key: "kubernetesIngestor.components.excludedNamespaces", Type: QuestionList, Prompt: "Enter excluded namespaces",
+				DefaultFrom: func(a *Answers) string { return strings.Join(defaults.KubernetesIngestor.ExcludedNamespaces, ",") }},
+			{ID: "disableDefaultWorkloadTypes", Key: "kubernetesIngestor.components.disableDefaultWorkloadTypes", Type: QuestionBool, Prompt: "Disable default workload types?",
+				Help: "The default workload types are Deployments, StatefulSets, DaemonSets, CronJobs and Crossplane claims."},
+			{ID: "onlyIngestAnnotatedResources", Key: "kubernetesIngestor.components.onlyIngestAnnotatedResources", Type: QuestionBool, Prompt: "Only ingest annotated resources?",
+				Help: "Only ingest resources with the terasky.backstage.io/add-to-catalog annotation."},
+			{ID: "addCustomWorkloadTypes", Type: QuestionBool, Prompt: "Add custom workload types?",
+				Heading: "Custom Workload Types Configurations"},
+			{Type: QuestionGroup, DependsOn: when("addCustomWorkloadTypes"), Questions: []Question{
+				{ID: "crdFiles", Prompt: "Enter CRD file or directory to discover workload types from (empty to enter them by hand)", Validate: validateCRDs},
+				{ID: "discoveredWorkloadTypes", Type: QuestionMultiSelect, Prompt: "Select the workload types to ingest",
+					DependsOn:   func(a *Answers) bool { return a.Get("crdFiles") != "" },
+					OptionsFrom: func(a *Answers) []string { return discoveredWorkloadTypes(a.Get("crdFiles")) }},
+				{ID: "customWorkloadTypes", Key: "kubernetesIngestor.components.customWorkloadTypes", Type: QuestionRepeat, Prompt: "Add another custom workload type?",
+					More: func(a *Answers, n int) bool { return a.Get("crdFiles") == "" },
+					Questions: []Question{
+						{ID: "group", Prompt: "Enter group", Validate: validateAPIGroup},
+						{ID: "apiVersion", Prompt: "Enter API version", Validate: validateAPIVersion},
+						{ID: "plural", Prompt: "Enter plural", Validate: validateWorkloadPlural},
+					}},
+			}},
+			{ID: "ingestAllClaims", Key: "kubernetesIngestor.crossplane.claims.ingestAllClaims", Type: QuestionBool, Prompt: "Ingest all claims?", Default: "true",
+				Heading: "Crossplane Ingestion Configurations"},
+			{ID: "convertDefaultValuesToPlaceholders", Key: "kubernetesIngestor.crossplane.xrds.convertDefaultValuesToPlaceholders", Type: QuestionBool, Prompt: "Convert default values to placeholders?", Default: "true"},
+			{ID: "xrds", Key: "kubernetesIngestor.crossplane.xrds.enabled", Type: QuestionBool, Prompt: "Enable XRDs?", Default: "true",
+				Help: "Generates software templates from Crossplane XRDs."},
+			{ID: "ingestAllXRDs", Key: "kubernetesIngestor.crossplane.xrds.ingestAllXRDs", Type: QuestionBool, Prompt: "Ingest all XRDs?", Default: "true"},
+			{Type: QuestionGroup, Questions: publishPhaseQuestions},
+		}},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.KubernetesIngestor = nil
+			return
+		}
+
+		components := ComponentsConfig{
+			Enabled: a.Bool("components"),
+			TaskRunner: TaskRunnerConfig{
+				Frequency: 10,
+				Timeout:   600,
+			},
+			ExcludedNamespaces:           a.List("excludedNamespaces"),
+			DisableDefaultWorkloadTypes:  a.Bool("disableDefaultWorkloadTypes"),
+			OnlyIngestAnnotatedResources: a.Bool("onlyIngestAnnotatedResources"),
+		}
+		for _, workloadType := range a.List("discoveredWorkloadTypes") {
+			components.CustomWorkloadTypes = append(components.CustomWorkloadTypes, parseWorkloadType(workloadType))
+		}
+		for _, workloadType := range a.Items("customWorkloadTypes") {
+			components.CustomWorkloadTypes = append(components.CustomWorkloadTypes, CustomWorkloadType{
+				Group:      workloadType.Get("group"),
+				ApiVersion: workloadType.Get("apiVersion"),
+				Plural:     workloadType.Get("plural"),
+			})
+		}
+
+		config.KubernetesIngestor = &KubernetesIngestorConfig{
+			Mappings:   buildMappings(a),
+			Components: components,
+			Crossplane: CrossplaneIngestorConfig{
+				Claims: CrossplaneClaimsConfig{
+					IngestAllClaims: a.Bool("ingestAllClaims"),
+				},
+				Xrds: CrossplaneXrdsConfig{
+					ConvertDefaultValuesToPlaceholders: a.Bool("convertDefaultValuesToPlaceholders"),
+					Enabled:                            a.Bool("xrds"),
+					IngestAllXRDs:                      a.Bool("ingestAllXRDs"),
+					TaskRunner: TaskRunnerConfig{
+						Frequency: 10,
+						Timeout:   600,
+					},
+					PublishPhase: buildPublishPhase(a),
+				},
+			},
+		}
+	},
+}
+
+func buildMappings(a *Answers) MappingsConfig {
+	return MappingsConfig{
+		NamespaceModel:           a.Get("namespaceModel"),
+		NameModel:                a.Get("nameModel"),
+		TitleModel:               a.Get("titleModel"),
+		SystemModel:              a.Get("systemModel"),
+		ReferencesNamespaceModel: a.Get("referencesNamespaceModel"),
+	}
+}
+
+// sampleClusterName defaults the mapping preview to a cluster configured in the Kubernetes section
+func sampleClusterName(a *Answers) string {
+	for _, locator := range a.Section("kubernetes").Items("locators") {
+		if clusters := locator.Items("clusters"); len(clusters) > 0 {
+			return clusters[0].Get("name")
+		}
+		if contexts := locator.List("contexts"); len(contexts) > 0 {
+			return contexts[0]
+		}
+	}
+	return "my-cluster"
+}
+
+// showMappingPreview prints the entities the chosen mapping models create for a sample workload
+func showMappingPreview(item *Answers) {
+	objects := []K8sObject{{Kind: item.Get("kind"), Metadata: K8sMetadata{Name: item.Get("name"), Namespace: item.Get("namespace")}}}
+	if manifest := item.Get("manifest"); manifest != "" {
+		// The manifest was validated when it was entered
+		objects, _ = readManifests(manifest)
+	}
+	for _, object := range objects {
+		if object.Metadata.Name == "" {
+			continue
+		}
+		fmt.Printf("%s %s/%s:\n", object.Kind, namespaceOrDefault(object.Metadata.Namespace), object.Metadata.Name)
+		printMappingPreview(os.Stdout, previewMapping(buildMappings(item), object, item.Get("cluster")))
+	}
+}
+
+var publishPhaseQuestions = []Question{
+	{ID: "publishTarget", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.target", Type: QuestionChoice, Prompt: "Enter publish target", Options: []string{"github", "gitlab", "bitbucket", "bitbucketCloud", "yaml"},
+		DefaultFrom: func(a *Answers) string { return defaults.PublishPhase.Target },
+		Heading:     "Template Publish Phase Configurations",
+		Help:        "Where templates generated from XRDs publish their manifests. yaml only offers the manifest for download."},
+	{Type: QuestionGroup, DependsOn: func(a *Answers) bool { return a.Get("publishTarget") != "yaml" }, Questions: []Question{
+		{ID: "publishHost", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.allowedTargets", Prompt: "Enter git host", DefaultFrom: func(a *Answers) string {
+			target := a.Get("publishTarget")
+			if target == defaults.PublishPhase.Target && defaults.PublishPhase.Host != "" {
+				return defaults.PublishPhase.Host
+			}
+			return publishTargetHosts[target]
+		}, Required: true, Help: "Bitbucket Server has no default host, enter the host of your instance."},
+		{ID: "allowRepoSelection", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.allowRepoSelection", Type: QuestionBool, Prompt: "Allow repo selection?",
+			Help: "Lets users pick the repository when running the template."},
+		{ID: "allowedTargets", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.allowedTargets", Type: QuestionList, Prompt: "Enter allowed targets", DefaultFrom: func(a *Answers) string { return a.Get("publishHost") }},
+		{ID: "publishWorkspace", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.git.repoUrl", Prompt: "Enter Bitbucket workspace", DependsOn: whenEquals("publishTarget", "bitbucketCloud")},
+		{ID: "publishProject", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.git.repoUrl", Prompt: "Enter Bitbucket project key", DependsOn: whenEquals("publishTarget", "bitbucket", "bitbucketCloud")},
+		{ID: "publishOwner", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.git.repoUrl", Prompt: "Enter repository owner (user, organization or group)", DependsOn: whenEquals("publishTarget", "github", "gitlab"),
+			DefaultFrom: func(a *Answers) string { return defaults.PublishPhase.Owner }},
+		{ID: "publishRepo", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.git.repoUrl", Prompt: "Enter repository name", DefaultFrom: func(a *Answers) string {
+			if strings.HasPrefix(a.Get("publishTarget"), "bitbucket") {
+				return ""
+			}
+			return defaults.PublishPhase.Repo
+		}},
+		{ID: "targetBranch", Key: "kubernetesIngestor.crossplane.xrds.publishPhase.git.targetBranch", Prompt: "Enter target branch", DefaultFrom: func(a *Answers) string { return defaults.PublishPhase.TargetBranch }},
+	}},
+}
+
+func buildPublishPhase(a *Answers) PublishPhaseConfig {
+	target := a.Get("publishTarget")
+	if target == "yaml" {
+		// The YAML target only offers the rendered manifest for download, no git settings are used
+		return PublishPhaseConfig{Target: target}
+	}
+	return PublishPhaseConfig{
+		AllowRepoSelection: a.Bool("allowRepoSelection"),
+		AllowedTargets:     a.List("allowedTargets"),
+		Target:             target,
+		Git: GitConfig{
+			RepoUrl:      buildPublishRepoUrl(a),
+			TargetBranch: a.Get("targetBranch"),
+		},
+	}
+}
+
+// buildPublishRepoUrl builds a repo URL in the query syntax expected by each Backstage publish action
+func buildPublishRepoUrl(a *Answers) string {
+	host := a.Get("publishHost")
+	switch a.Get("publishTarget") {
+	case "bitbucket":
+		return buildRepoUrl(host, "project", a.Get("publishProject"), "repo", a.Get("publishRepo"))
+	case "bitbucketCloud":
+		return buildRepoUrl(host, "workspace", a.Get("publishWorkspace"), "project", a.Get("publishProject"), "repo", a.Get("publishRepo"))
+	default:
+		return buildRepoUrl(host, "owner", a.Get("publishOwner"), "repo", a.Get("publishRepo"))
+	}
+}
+
+// buildRepoUrl keeps the parameters in the given order, unlike url.Values.Encode
+func buildRepoUrl(host string, keyValues ...string) string {
+	var params []string
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		params = append(params, keyValues[i]+"="+url.QueryEscape(keyValues[i+1]))
+	}
+	return host + "?" + strings.Join(params, "&")
+}
+
+var scaleopsSection = Section{
+	ID:    "scaleops",
+	Title: "ScaleOps",
+	Questions: []Question{
+		{ID: "enabled", Key: "scaleops", Type: QuestionBool, Prompt: "Configure ScaleOps?", Preset: "scaleops",
+			Help: "Shows ScaleOps cost and rightsizing data on Kubernetes workloads."},
+		{Type: QuestionGroup, DependsOn: when("enabled"), Questions: []Question{
+			{ID: "baseUrl", Key: "scaleops.baseUrl", Prompt: "Enter ScaleOps base URL", DefaultFrom: func(a *Answers) string { return defaults.Scaleops.BaseUrl }, Validate: validateURL},
+			{ID: "currencyPrefix", Key: "scaleops.currencyPrefix", Prompt: "Enter currency prefix", DefaultFrom: func(a *Answers) string { return defaults.Scaleops.CurrencyPrefix }},
+			{ID: "linkToDashboard", Key: "scaleops.linkToDashboard", Type: QuestionBool, Prompt: "Enable dashboard linking?", Default: "true"},
+			{ID: "authentication", Key: "scaleops.authentication.enabled", Type: QuestionBool, Prompt: "Enable authentication?"},
+		}},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.Scaleops = nil
+			return
+		}
+		config.Scaleops = &ScaleopsConfig{
+			BaseUrl:         a.Get("baseUrl"),
+			CurrencyPrefix:  a.Get("currencyPrefix"),
+			LinkToDashboard: a.Bool("linkToDashboard"),
+			Authentication: AuthenticationConfig{
+				Enabled: a.Bool("authentication"),
+			},
+		}
+	},
+}
+
+// endpointPreset returns the preset a proxy endpoint starts from
+func endpointPreset(a *Answers) ProxyPreset {
+	if preset, ok := proxyPresets[a.Get("preset")]; ok {
+		return preset
+	}
+	return ProxyPreset{Path: "/scaleops", Secure: true, Credentials: "require"}
+}
+
+var proxySection = Section{
+	ID:    "proxy",
+	Title: "Backend Proxy",
+	Questions: []Question{
+		{ID: "enabled", Key: "proxy", Type: QuestionBool, Prompt: "Configure proxy endpoints?", Preset: "proxy",
+			Help: "Forwards /api/proxy/<path> requests from the frontend to other services."},
+		{ID: "endpoints", Key: "proxy.endpoints", Type: QuestionRepeat, Prompt: "Add proxy endpoint?", DependsOn: when("enabled"),
+			More: func(a *Answers, n int) bool { return true },
+			Questions: []Question{
+				{ID: "preset", Type: QuestionChoice, Prompt: "Start from a preset", Options: []string{"scaleops", "vcf-automation", "none"}, Default: "none"},
+				{ID: "path", Key: "proxy.endpoints", Prompt: "Enter endpoint path (e.g., /scaleops)", DefaultFrom: func(a *Answers) string { return endpointPreset(a).Path },
+					Validate: func(value string, a *Answers) error {
+						for _, endpoint := range a.Items("endpoints") {
+							if endpoint.Get("path") == value {
+								return fmt.Errorf("endpoint %s is already configured", value)
+							}
+						}
+						return nil
+					}},
+				{ID: "target", Key: "proxy.endpoints.*.target", Prompt: "Enter target URL", DefaultFrom: func(a *Answers) string { return endpointPreset(a).Target }, Validate: validateURL},
+				{ID: "changeOrigin", Key: "proxy.endpoints.*.changeOrigin", Type: QuestionBool, Prompt: "Enable change origin?", Default: "true"},
+				{ID: "credentials", Key: "proxy.endpoints.*.credentials", Type: QuestionChoice, Prompt: "Enter credentials mode", Options: []string{"require", "forward", "dangerously-allow-unauthenticated"},
+					DefaultFrom: func(a *Answers) string { return endpointPreset(a).Credentials },
+					Help:        "require needs a Backstage identity, forward also passes it to the target, dangerously-allow-unauthenticated needs none."},
+				{ID: "secure", Key: "proxy.endpoints.*.secure", Type: QuestionBool, Prompt: "Verify target TLS certificate?", DefaultFrom: func(a *Answers) string { return boolString(endpointPreset(a).Secure) },
+					Help: "Answer no only for targets with self-signed certificates, such as lab VCF Automation instances."},
+				{ID: "addAuthorization", Type: QuestionBool, Prompt: "Add Authorization header?"},
+				{ID: "authorization", Key: "proxy.endpoints.*.headers.Authorization", Prompt: "Enter Authorization header value", DependsOn: when("addAuthorization"),
+					DefaultFrom: func(a *Answers) string { return fmt.Sprintf("${%s}", envVarName(a.Get("path"), "TOKEN")) }},
+				{ID: "headers", Key: "proxy.endpoints.*.headers", Type: QuestionRepeat, Prompt: "Add another request header?", Questions: []Question{
+					{ID: "name", Prompt: "Enter header name"},
+					{ID: "value", Prompt: "Enter header value"},
+				}},
+				{ID: "allowedMethods", Key: "proxy.endpoints.*.allowedMethods", Type: QuestionList, Prompt: "Enter allowed methods", DefaultFrom: func(a *Answers) string { return strings.Join(endpointPreset(a).AllowedMethods, ",") }},
+				{ID: "allowedHeaders", Key: "proxy.endpoints.*.allowedHeaders", Type: QuestionList, Prompt: "Enter allowed headers"},
+				{ID: "rewrite", Type: QuestionBool, Prompt: "Rewrite the proxied path?"},
+				{ID: "rewritePath", Key: "proxy.endpoints.*.pathRewrite", Prompt: "Enter replacement path", Default: "/", DependsOn: when("rewrite")},
+			}},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.Proxy = nil
+			return
+		}
+		endpoints := make(map[string]EndpointConfig)
+		for _, item := range a.Items("endpoints") {
+			path := item.Get("path")
+			endpoint := EndpointConfig{
+				Target:         item.Get("target"),
+				ChangeOrigin:   item.Bool("changeOrigin"),
+				Credentials:    item.Get("credentials"),
+				AllowedMethods: item.List("allowedMethods"),
+				AllowedHeaders: item.List("allowedHeaders"),
+			}
+			if !item.Bool("secure") {
+				secure := false
+				endpoint.Secure = &secure
+			}
+			if item.Bool("addAuthorization") || len(item.Items("headers")) > 0 {
+				endpoint.Headers = make(map[string]string)
+			}
+			if item.Bool("addAuthorization") {
+				endpoint.Headers["Authorization"] = item.Get("authorization")
+			}
+			for _, header := range item.Items("headers") {
+				endpoint.Headers[header.Get("name")] = header.Get("value")
+			}
+			if item.Bool("rewrite") {
+				endpoint.PathRewrite = map[string]string{
+					fmt.Sprintf("^/api/proxy%s", path): item.Get("rewritePath"),
+				}
+			}
+			endpoints[path] = endpoint
+		}
+		config.Proxy = &ProxyConfig{
+			Endpoints: endpoints,
+		}
+	},
+}
+
+var devpodSection = Section{
+	ID:    "devpod",
+	Title: "Devpod",
+	Questions: []Question{
+		{ID: "enabled", Key: "devpod", Type: QuestionBool, Prompt: "Configure Devpod?", Preset: "devpod",
+			Help: "Adds Open in DevPod links to components."},
+		{ID: "defaultIDE", Key: "devpod.defaultIDE", Prompt: "Enter default IDE", DependsOn: when("enabled"), DefaultFrom: func(a *Answers) string { return defaults.Devpod.DefaultIDE }},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.Devpod = nil
+			return
+		}
+		config.Devpod = &DevpodConfig{
+			DefaultIDE: a.Get("defaultIDE"),
+		}
+	},
+}
+
+var permissionSection = Section{
+	ID:    "permission",
+	Title: "Permission Framework",
+	Questions: []Question{
+		{ID: "enabled", Key: "permission.enabled", Type: QuestionBool, Prompt: "Configure permissions?", Preset: "permission",
+			Help: "Enables the permission framework with the RBAC plugin."},
+		{Type: QuestionGroup, DependsOn: when("enabled"), Questions: []Question{
+			{ID: "policiesCSVFile", Key: "permission.rbac.policies-csv-file", Prompt: "Enter policies CSV file path", DefaultFrom: func(a *Answers) string { return defaults.Permission.PoliciesCSVFile },
+				Heading: "RBAC Plugin Configurations"},
+			{ID: "policyFileReload", Key: "permission.rbac.policyFileReload", Type: QuestionBool, Prompt: "Enable policy file reload?", Default: "true"},
+			{ID: "pluginsWithPermission", Key: "permission.rbac.pluginsWithPermission", Type: QuestionList, Prompt: "Enter plugins with permission",
+				DefaultFrom: func(a *Answers) string { return strings.Join(defaults.Permission.PluginsWithPermission, ",") }},
+			{ID: "admins", Key: "permission.rbac.admin.users", Type: QuestionRepeat, Prompt: "Add admin user?", More: func(a *Answers, n int) bool { return true },
+				Questions: []Question{{ID: "name", Prompt: "Enter admin user name (e.g., user:default/username)"}}},
+			{ID: "superAdmins", Key: "permission.rbac.superAdmin.users", Type: QuestionRepeat, Prompt: "Add super admin user?", More: func(a *Answers, n int) bool { return true },
+				Questions: []Question{{ID: "name", Prompt: "Enter super admin user name (e.g., user:default/username)"}}},
+		}},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.Permission = PermissionConfig{}
+			return
+		}
+		users := func(id string) []UserConfig {
+			var users []UserConfig
+			for _, item := range a.Items(id) {
+				users = append(users, UserConfig{Name: item.Get("name")})
+			}
+			return users
+		}
+		config.Permission = PermissionConfig{
+			Enabled: true,
+			Rbac: RbacPermissionConfig{
+				PoliciesCSVFile:       a.Get("policiesCSVFile"),
+				PolicyFileReload:      a.Bool("policyFileReload"),
+				PluginsWithPermission: a.List("pluginsWithPermission"),
+				Admin:                 AdminConfig{Users: users("admins")},
+				SuperAdmin:            AdminConfig{Users: users("superAdmins")},
+			},
+		}
+	},
+}
+
+var crossplaneSection = Section{
+	ID:    "crossplane",
+	Title: "Crossplane",
+	Questions: []Question{
+		{ID: "enabled", Key: "crossplane", Type: QuestionBool, Prompt: "Configure Crossplane?", Preset: "crossplane",
+			Help: "Shows Crossplane claims, composite resources and managed resources of components."},
+		{ID: "enablePermissions", Key: "crossplane.enablePermissions", Type: QuestionBool, Prompt: "Enable Crossplane permissions?", Default: "true", DependsOn: when("enabled")},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.Crossplane = nil
+			return
+		}
+		config.Crossplane = &CrossplaneConfig{
+			EnablePermissions: a.Bool("enablePermissions"),
+		}
+	},
+}
+
+var kyvernoSection = Section{
+	ID:    "kyverno",
+	Title: "Kyverno",
+	Questions: []Question{
+		{ID: "enabled", Key: "kyverno", Type: QuestionBool, Prompt: "Configure Kyverno?", Preset: "kyverno",
+			Help: "Shows Kyverno policy reports of components."},
+		{ID: "enablePermissions", Key: "kyverno.enablePermissions", Type: QuestionBool, Prompt: "Enable Kyverno permissions?", Default: "true", DependsOn: when("enabled")},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.Kyverno = nil
+			return
+		}
+		config.Kyverno = &KyvernoConfig{
+			EnablePermissions: a.Bool("enablePermissions"),
+		}
+	},
+}
+
+var vcfAutomationSection = Section{
+	ID:    "vcfAutomation",
+	Title: "VCF Automation",
+	Questions: []Question{
+		{ID: "enabled", Key: "vcfAutomation", Type: QuestionBool, Prompt: "Configure VCF Automation?", Preset: "vcfAutomation",
+			Help: "Ingests VCF Automation deployments and projects into the catalog."},
+		{ID: "enablePermissions", Key: "vcfAutomation.enablePermissions", Type: QuestionBool, Prompt: "Enable VCF Automation permissions?", Default: "true", DependsOn: when("enabled")},
+		{ID: "instances", Key: "vcfAutomation.instances", Type: QuestionRepeat, Prompt: "Add a VCF Automation instance?", DependsOn: when("enabled"),
+			More: func(a *Answers, n int) bool { return n == 0 },
+			Questions: []Question{
+				{ID: "name", Key: "vcfAutomation.instances.name", Prompt: "Enter instance name"},
+				{ID: "baseUrl", Key: "vcfAutomation.instances.baseUrl", Prompt: "Enter VCF Automation base URL", Validate: validateURL},
+				{ID: "majorVersion", Key: "vcfAutomation.instances.majorVersion", Type: QuestionChoice, Prompt: "Enter VCF Automation major version", Options: []string{"8", "9"}, Default: "9"},
+				{ID: "username", Key: "vcfAutomation.instances.authentication.username", Prompt: "Enter username"},
+				{ID: "password", Key: "vcfAutomation.instances.authentication.password", Prompt: "Enter password", DefaultFrom: func(a *Answers) string { return fmt.Sprintf("${%s}", envVarName("VCFA", a.Get("name"), "PASSWORD")) }},
+				{ID: "orgName", Key: "vcfAutomation.instances.orgName", Prompt: "Enter organization name", DependsOn: whenEquals("majorVersion", "9"),
+					Help: "VCF Automation 9 authenticates against an organization."},
+				{ID: "domain", Key: "vcfAutomation.instances.authentication.domain", Prompt: "Enter authentication domain", DependsOn: whenEquals("majorVersion", "8"),
+					Help: "VCF Automation 8 authenticates against an identity domain."},
+			}},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.VcfAutomation = nil
+			return
+		}
+		vcf := &VcfAutomationConfig{
+			EnablePermissions: a.Bool("enablePermissions"),
+		}
+		for _, item := range a.Items("instances") {
+			vcf.Instances = append(vcf.Instances, VcfAutomationInstance{
+				Name:         item.Get("name"),
+				BaseUrl:      item.Get("baseUrl"),
+				MajorVersion: item.Int("majorVersion"),
+				OrgName:      item.Get("orgName"),
+				Authentication: VcfAuthenticationConfig{
+					Username: item.Get("username"),
+					Password: item.Get("password"),
+					Domain:   item.Get("domain"),
+				},
+			})
+		}
+		config.VcfAutomation = vcf
+	},
+}
+
+var scaffolderSection = Section{
+	ID:    "scaffolder",
+	Title: "Scaffolder",
+	Questions: []Question{
+		{ID: "enabled", Key: "scaffolder", Type: QuestionBool, Prompt: "Configure scaffolder?", Preset: "scaffolder",
+			Help: "Sets scaffolder defaults for software templates."},
+		{Type: QuestionGroup, DependsOn: when("enabled"), Questions: []Question{
+			{ID: "defaultCommitMessage", Key: "scaffolder.defaultCommitMessage", Prompt: "Enter default commit message", Default: "Initial commit"},
+			{ID: "concurrentTasksLimit", Key: "scaffolder.concurrentTasksLimit", Prompt: "Enter concurrent tasks limit", Default: "10", Validate: validateNumber},
+			{ID: "setDefaultAuthor", Key: "scaffolder.defaultAuthor", Type: QuestionBool, Prompt: "Set a default git author?"},
+			{Type: QuestionGroup, DependsOn: when("setDefaultAuthor"), Questions: []Question{
+				{ID: "authorName", Key: "scaffolder.defaultAuthor.name", Prompt: "Enter default author name", Default: "Backstage Scaffolder"},
+				{ID: "authorEmail", Key: "scaffolder.defaultAuthor.email", Prompt: "Enter default author email", Default: "scaffolder@backstage.io"},
+			}},
+			{ID: "workingDirectory", Key: "backend.workingDirectory", Prompt: "Enter scaffolder working directory (empty for the OS temp dir)"},
+			{ID: "teraskyUtils", Key: "kubernetesIngestor.genericCRDTemplates", Type: QuestionBool, Prompt: "Register TeraSky utils actions settings (claim-templating, crd-templating, catalog-info-cleaner)?",
+				DefaultFrom: func(a *Answers) string { return boolString(a.Section("kubernetesIngestor").Bool("enabled")) },
+				Help:        "The terasky:claim-template and terasky:crd-template actions publish with the Kubernetes Ingestor publish phase settings."},
+		}},
+	},
+	Build: func(a *Answers, config *Config) {
+		if !a.Bool("enabled") {
+			config.Scaffolder = ScaffolderConfig{}
+			return
+		}
+		scaffolder := ScaffolderConfig{
+			DefaultCommitMessage: a.Get("defaultCommitMessage"),
+			ConcurrentTasksLimit: a.Int("concurrentTasksLimit"),
+		}
+		if a.Bool("setDefaultAuthor") {
+			scaffolder.DefaultAuthor = &ScaffolderAuthorConfig{
+				Name:  a.Get("authorName"),
+				Email: a.Get("authorEmail"),
+			}
+		}
+		config.Backend.WorkingDirectory = a.Get("workingDirectory")
+		if a.Bool("teraskyUtils") {
+			registerTeraskyUtilsSettings(config)
+		}
+		config.Scaffolder = scaffolder
+	},
+}
+
+// registerTeraskyUtilsSettings makes sure the publish phase settings read by the
+// terasky:claim-template and terasky:crd-template actions are present.
+func registerTeraskyUtilsSettings(config *Config) {
+	if config.KubernetesIngestor == nil {
+		fmt.Println("Kubernetes Ingestor is not configured, the TeraSky utils actions will publish to github using the template parameters only")
+		return
+	}
+	if config.KubernetesIngestor.GenericCRDTemplates == nil {
+		config.KubernetesIngestor.GenericCRDTemplates = &GenericCRDTemplatesConfig{
+			PublishPhase: config.KubernetesIngestor.Crossplane.Xrds.PublishPhase,
+		}
+	}
+}
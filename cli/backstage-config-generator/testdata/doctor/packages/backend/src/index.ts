@@ -0,0 +1,10 @@
+import { createBackend } from '@backstage/backend-defaults';
+
+const backend = createBackend();
+
+backend.add(import('@backstage/plugin-app-backend'));
+backend.add(
+  import('@terasky/backstage-plugin-kubernetes-ingestor'),
+);
+// backend.add(import('@terasky/backstage-plugin-vcf-automation-backend'));
+backend.start();
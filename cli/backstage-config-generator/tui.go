@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// tuiEnabled switches boolean and choice prompts to arrow-key selection
+var tuiEnabled bool
+
+// tuiAvailable reports whether both ends are a terminal that can be switched to raw mode
+func tuiAvailable() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// makeRaw puts the terminal in raw mode and returns a function restoring the previous state
+func makeRaw() (func(), error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	return func() { term.Restore(fd, state) }, nil
+}
+
+// selectOption renders options as a list navigated with the arrow keys (or j/k)
+// and returns the index of the option confirmed with enter.
+func selectOption(prompt string, options []string, selected int) int {
+	return selectOptionWithHelp(prompt, options, selected, "")
+}
+
+// selectOptionWithHelp is selectOption printing help above the list when "?" is pressed
+func selectOptionWithHelp(prompt string, options []string, selected int, help string) int {
+	restore, err := makeRaw()
+	if err != nil {
+		tuiEnabled = false
+		fmt.Fprintf(os.Stderr, "Terminal does not support raw mode, using plain prompts: %v\n", err)
+		return plainSelectOption(prompt, options, selected, help)
+	}
+	defer restore()
+
+	render := func() {
+		for i, option := range options {
+			if i == selected {
+				fmt.Printf("\r\033[K\033[1m> %s\033[0m\r\n", option)
+			} else {
+				fmt.Printf("\r\033[K  %s\r\n", option)
+			}
+		}
+	}
+	fmt.Printf("%s\r\n", prompt)
+	render()
+
+	buf := make([]byte, 3)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return selected
+		}
+		key := string(buf[:n])
+		switch key {
+		case "\r", "\n":
+			// Collapse the list into a single answered line
+			fmt.Printf("\033[%dA\r\033[J%s %s\r\n", len(options)+1, prompt, options[selected])
+			return selected
+		case "\x03":
+			restore()
+			fmt.Println()
+			os.Exit(130)
+		case "\033[A", "k":
+			selected = (selected + len(options) - 1) % len(options)
+		case "\033[B", "j":
+			selected = (selected + 1) % len(options)
+		case "?":
+			if help == "" {
+				continue
+			}
+			// Raw mode does not translate newlines, so the help is printed line by line
+			fmt.Printf("\033[%dA\r\033[J", len(options)+1)
+			for _, line := range strings.Split(strings.TrimSuffix(help, "\n"), "\n") {
+				fmt.Printf("%s\r\n", line)
+			}
+			fmt.Printf("%s\r\n", prompt)
+			render()
+			continue
+		default:
+			continue
+		}
+		fmt.Printf("\033[%dA", len(options))
+		render()
+	}
+}
+
+func plainSelectOption(prompt string, options []string, selected int, help string) int {
+	for i, option := range options {
+		fmt.Printf("  %d) %s\n", i+1, option)
+	}
+	for {
+		input := promptString(prompt+" (number)", fmt.Sprint(selected+1))
+		if input == "?" && help != "" {
+			fmt.Print(help)
+			continue
+		}
+		var n int
+		if _, err := fmt.Sscan(input, &n); err == nil && n >= 1 && n <= len(options) {
+			return n - 1
+		}
+		fmt.Printf("Invalid choice: %s\n", input)
+	}
+}
+
+// runTUI walks through the sections with the option to go back, then shows the
+// generated YAML for review. It returns false when the user cancels.
+func runTUI(config *Config, sections []Section) (bool, error) {
+	done := make([]bool, len(sections))
+	// run runs a section and builds the later sections again, a section such as
+	// the Kubernetes Ingestor replaces the settings later sections added to it
+	run := func(i int, f Frontend) error {
+		if err := sections[i].RunWith(f, config); err != nil {
+			return err
+		}
+		done[i] = true
+		for j := i + 1; j < len(sections); j++ {
+			if done[j] {
+				sections[j].Build(answers.items[sections[j].ID][0], config)
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < len(sections); {
+		if err := run(i, activeFrontend()); err != nil {
+			return false, err
+		}
+		fmt.Println("")
+		switch selectOption(fmt.Sprintf("Finished %s:", sections[i].Title), []string{"Continue", "Redo this section", "Back to previous section", "Skip to review"}, 0) {
+		case 0:
+			i++
+		case 2:
+			if i > 0 {
+				i--
+			}
+		case 3:
+			// The remaining sections take their defaults, as if every answer was left blank
+			for i++; i < len(sections); i++ {
+				if done[i] {
+					continue
+				}
+				if err := run(i, &defaultsFrontend{}); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+
+	for {
+		yamlData, err := marshalConfig(config)
+		fmt.Print("\033[H\033[2J")
+		fmt.Println("Review Configuration")
+		fmt.Println("====================")
+		if err != nil {
+			// Secret references such as vault ones can still be resolved when writing
+			fmt.Printf("The configuration cannot be shown: %v\n\n", err)
+		} else {
+			fmt.Println(string(yamlData))
+		}
+
+		switch selectOption("Write this configuration?", []string{"Write", "Edit a section", "Cancel"}, 0) {
+		case 0:
+			return true, nil
+		case 1:
+			var titles []string
+			for _, section := range sections {
+				titles = append(titles, section.Title)
+			}
+			if err := run(selectOption("Section to edit:", titles, 0), activeFrontend()); err != nil {
+				return false, err
+			}
+		case 2:
+			return false, nil
+		}
+	}
+}